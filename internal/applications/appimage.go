@@ -0,0 +1,225 @@
+package applications
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// appImageIconLimit bounds the size of an icon extracted from an AppImage.
+const appImageIconLimit = 4 << 20
+
+// parseAppImage reads the desktop entry embedded in a type 2 AppImage without
+// executing it. When the entry cannot be read the AppImage is still listed
+// under its file name; only damaged images report an error.
+func parseAppImage(path string, env desktopEnv) (Application, error) {
+	fallback := Application{
+		ID:     filepath.Base(path),
+		Name:   strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		Exec:   joinExec([]execToken{{text: path, quoted: true}}),
+		Path:   path,
+		Source: SourceAppImage,
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fallback, err
+	}
+	defer file.Close()
+
+	offset, err := elfSize(file)
+	if err != nil {
+		// Type 1 AppImages are ISO images; list them without metadata.
+		return fallback, nil
+	}
+	squash, err := openSquashFS(file, offset)
+	if errors.Is(err, errUnsupportedSquashFS) {
+		return fallback, nil
+	}
+	if err != nil {
+		return fallback, err
+	}
+	entries, err := squash.rootEntries()
+	if err != nil {
+		return fallback, err
+	}
+
+	var desktopName string
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.name, ".desktop") {
+			desktopName = entry.name
+			break
+		}
+	}
+	if desktopName == "" {
+		return fallback, nil
+	}
+	data, err := squash.readFile(entries, desktopName, 1<<20)
+	if err != nil {
+		return fallback, err
+	}
+	groups, err := parseDesktopGroups(bytes.NewReader(data))
+	if err != nil {
+		return fallback, err
+	}
+	entry, ok := groups["Desktop Entry"]
+	if !ok {
+		return fallback, nil
+	}
+	if entry.bool("Hidden") || entry.bool("NoDisplay") {
+		return Application{}, errSkipApplication
+	}
+	if !env.shows(entry.list("OnlyShowIn"), entry.list("NotShowIn")) {
+		return Application{}, errSkipApplication
+	}
+
+	app := fallback
+	if name := entry.localized("Name", env.locales); name != "" {
+		app.Name = name
+		app.UntranslatedName = entry.string("Name")
+	}
+	app.GenericName = entry.localized("GenericName", env.locales)
+	app.Comment = entry.localized("Comment", env.locales)
+	app.Keywords = mergeLists(entry.localizedList("Keywords", env.locales), entry.list("Keywords"))
+	app.Categories = entry.list("Categories")
+	app.Terminal = entry.bool("Terminal")
+	app.StartupNotify = entry.bool("StartupNotify")
+	app.StartupWMClass = entry.string("StartupWMClass")
+	app.MimeTypes = entry.list("MimeType")
+	if tokens, err := splitExec(entry.string("Exec")); err == nil && len(tokens) > 0 {
+		// The embedded Exec names the binary inside the image, so run the
+		// image itself with the same arguments.
+		tokens[0] = execToken{text: path, quoted: true}
+		app.Exec = joinExec(tokens)
+	}
+	app.IconName = entry.string("Icon")
+	app.IconPath = extractAppImageIcon(squash, entries, path, app.IconName)
+	return app, nil
+}
+
+// extractAppImageIcon copies the AppImage's icon into the cache directory and
+// returns its path, or "" when there is none. The icon named by the desktop
+// entry is preferred over .DirIcon.
+func extractAppImageIcon(squash *squashFS, entries []squashDirEntry, path, iconName string) string {
+	dir, err := cacheDir()
+	if err != nil {
+		return ""
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s\x00%d\x00%d", path, info.Size(), info.ModTime().UnixNano()))
+	key := hex.EncodeToString(sum[:12])
+
+	var candidates []string
+	if iconName != "" && !strings.ContainsRune(iconName, '/') {
+		for _, ext := range iconExtensions {
+			candidates = append(candidates, iconName+ext)
+		}
+	}
+	candidates = append(candidates, ".DirIcon")
+
+	for _, name := range candidates {
+		ext := filepath.Ext(name)
+		if name == ".DirIcon" {
+			ext = ""
+		}
+		for _, cached := range []string{".png", ".svg", ".xpm"} {
+			target := filepath.Join(dir, "appimage-icons", key+cached)
+			if (ext == "" || ext == cached) && fileExists(target) {
+				return target
+			}
+		}
+		data, err := squash.readFile(entries, name, appImageIconLimit)
+		if err != nil {
+			continue
+		}
+		if ext == "" {
+			ext = sniffIconExtension(data)
+			if ext == "" {
+				continue
+			}
+		}
+		target := filepath.Join(dir, "appimage-icons", key+ext)
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return ""
+		}
+		if err := os.WriteFile(target, data, 0o644); err != nil {
+			return ""
+		}
+		return target
+	}
+	return ""
+}
+
+func sniffIconExtension(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return ".png"
+	case bytes.HasPrefix(data, []byte("/* XPM */")):
+		return ".xpm"
+	case bytes.Contains(data[:min(len(data), 512)], []byte("<svg")):
+		return ".svg"
+	}
+	return ""
+}
+
+// elfSize returns the size of the ELF runtime at the start of an AppImage,
+// which is where the SquashFS image begins. The size is the end of the section
+// header table, as computed by the AppImage runtime itself.
+func elfSize(file *os.File) (int64, error) {
+	ident := make([]byte, 16)
+	if _, err := file.ReadAt(ident, 0); err != nil {
+		return 0, err
+	}
+	if !bytes.HasPrefix(ident, []byte("\x7fELF")) {
+		return 0, fmt.Errorf("not an ELF file")
+	}
+	var order binary.ByteOrder
+	switch ident[5] {
+	case 1:
+		order = binary.LittleEndian
+	case 2:
+		order = binary.BigEndian
+	default:
+		return 0, fmt.Errorf("unknown ELF byte order %d", ident[5])
+	}
+
+	var shoff int64
+	var shentsize, shnum uint16
+	switch ident[4] {
+	case 1:
+		header := make([]byte, 52)
+		if _, err := file.ReadAt(header, 0); err != nil {
+			return 0, err
+		}
+		shoff = int64(order.Uint32(header[32:]))
+		shentsize, shnum = order.Uint16(header[46:]), order.Uint16(header[48:])
+	case 2:
+		header := make([]byte, 64)
+		if _, err := file.ReadAt(header, 0); err != nil {
+			return 0, err
+		}
+		shoff = int64(order.Uint64(header[40:]))
+		shentsize, shnum = order.Uint16(header[58:]), order.Uint16(header[60:])
+	default:
+		return 0, fmt.Errorf("unknown ELF class %d", ident[4])
+	}
+
+	offset := shoff + int64(shentsize)*int64(shnum)
+	magic := make([]byte, 4)
+	if _, err := file.ReadAt(magic, offset); err != nil {
+		return 0, err
+	}
+	if string(magic) != "hsqs" {
+		return 0, fmt.Errorf("no squashfs image at offset %d", offset)
+	}
+	return offset, nil
+}
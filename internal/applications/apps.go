@@ -3,68 +3,68 @@ package applications
 import (
 	"bufio"
 	"errors"
-	"fmt"
-	"io/fs"
+	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
-	"runtime"
 	"sort"
 	"strings"
-	"sync"
 )
 
 // Application represents a desktop launcher entry available on the system.
 type Application struct {
+	// ID is the desktop file ID, e.g. "firefox.desktop" or "kde-foo.desktop".
+	ID string
+	// Name is the name in the user's locale; UntranslatedName is the plain
+	// Name key so either spelling can be searched.
+	Name             string
+	UntranslatedName string
+	GenericName      string
+	Comment          string
+	Keywords         []string
+	Categories       []string
+	Exec             string
+	IconName         string
+	IconPath         string
+	Path             string
+	// Terminal is set when the entry must run inside a terminal emulator.
+	Terminal bool
+	// WorkingDir is the Path key: the directory the program is started in.
+	WorkingDir string
+	// StartupNotify is set when the application completes startup
+	// notification, so the launcher may pass it a startup ID.
+	StartupNotify bool
+	// StartupWMClass is the WM class the application's main window is
+	// expected to have.
+	StartupWMClass string
+	// MimeTypes lists the MIME types the application can open.
+	MimeTypes []string
+	// NoDisplay marks entries that exist only to handle MIME types and are
+	// not shown in the launcher's list.
+	NoDisplay bool
+	// Agent marks macOS apps without a Dock icon (LSUIElement), which show
+	// nothing but a menu bar item when launched.
+	Agent   bool
+	Actions []Action
+	// Source names the Source the application was discovered by, such as
+	// SourceFlatpak.
+	Source string
+	// ActionID is set on entries derived from a [Desktop Action] group, whose
+	// ID is that of their application.
+	ActionID string
+}
+
+// Action is an additional launch mode declared through a desktop file's
+// Actions key, such as "New Private Window".
+type Action struct {
+	ID       string
 	Name     string
 	Exec     string
 	IconName string
 	IconPath string
-	Path     string
 }
 
-// List returns the applications discovered on the current system by scanning
-// standard freedesktop application directories.
-func List() ([]Application, error) {
-	if runtime.GOOS == "darwin" {
-		return listDarwin()
-	}
-
-	dirs := desktopDirs()
-	seenPaths := make(map[string]struct{})
-	apps := make([]Application, 0, 128)
-	var errs []error
-
-	for _, dir := range dirs {
-		entries, err := os.ReadDir(dir)
-		if err != nil {
-			if errors.Is(err, os.ErrNotExist) {
-				continue
-			}
-			errs = append(errs, fmt.Errorf("read dir %s: %w", dir, err))
-			continue
-		}
-		for _, entry := range entries {
-			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".desktop") {
-				continue
-			}
-			path := filepath.Join(dir, entry.Name())
-			if _, ok := seenPaths[path]; ok {
-				continue
-			}
-			seenPaths[path] = struct{}{}
-
-			app, err := parseDesktopFile(path)
-			if err != nil {
-				if errors.Is(err, errSkipApplication) {
-					continue
-				}
-				errs = append(errs, fmt.Errorf("parse %s: %w", path, err))
-				continue
-			}
-			apps = append(apps, app)
-		}
-	}
-
+func sortApplications(apps []Application) {
 	sort.Slice(apps, func(i, j int) bool {
 		nameI := strings.ToLower(apps[i].Name)
 		nameJ := strings.ToLower(apps[j].Name)
@@ -73,297 +73,357 @@ func List() ([]Application, error) {
 		}
 		return nameI < nameJ
 	})
-
-	if len(errs) > 0 {
-		return apps, errors.Join(errs...)
-	}
-	return apps, nil
 }
 
 var errSkipApplication = errors.New("skip application")
 
-func parseDesktopFile(path string) (Application, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return Application{}, err
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	scanner.Buffer(make([]byte, 0, 4096), 1024*1024)
-
-	var (
-		inDesktopEntry bool
-		name           string
-		exec           string
-		iconName       string
-		appType        string
-		hidden         bool
-		noDisplay      bool
-	)
+// desktopEnv captures the session details that decide how desktop entries are
+// localized and which of them are shown.
+type desktopEnv struct {
+	locales  []string
+	desktops []string
+}
 
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
+func currentDesktopEnv() desktopEnv {
+	var desktops []string
+	for _, name := range strings.Split(os.Getenv("XDG_CURRENT_DESKTOP"), ":") {
+		if name = strings.TrimSpace(name); name != "" {
+			desktops = append(desktops, name)
 		}
-		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
-			inDesktopEntry = line == "[Desktop Entry]"
-			continue
-		}
-		if !inDesktopEntry {
-			continue
+	}
+	return desktopEnv{locales: messageLocales(), desktops: desktops}
+}
+
+// shows reports whether an entry with the given OnlyShowIn and NotShowIn lists
+// should be displayed in the current desktop.
+func (e desktopEnv) shows(onlyShowIn, notShowIn []string) bool {
+	for _, desktop := range e.desktops {
+		if containsFold(notShowIn, desktop) {
+			return false
 		}
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) != 2 {
-			continue
+	}
+	if len(onlyShowIn) == 0 {
+		return true
+	}
+	for _, desktop := range e.desktops {
+		if containsFold(onlyShowIn, desktop) {
+			return true
 		}
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
+	}
+	return false
+}
 
-		switch {
-		case key == "Type":
-			appType = value
-		case key == "Name":
-			name = value
-		case strings.HasPrefix(key, "Name[") && name == "":
-			name = value
-		case key == "Exec":
-			exec = sanitiseExec(value)
-		case key == "Icon":
-			iconName = value
-		case key == "Hidden":
-			hidden = strings.EqualFold(value, "true")
-		case key == "NoDisplay":
-			noDisplay = strings.EqualFold(value, "true")
+func containsFold(values []string, target string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, target) {
+			return true
 		}
 	}
+	return false
+}
 
-	if err := scanner.Err(); err != nil {
+// desktopFileID derives the desktop file ID of path relative to the
+// applications directory it was found in, turning subdirectories into
+// dash-separated prefixes (kde/foo.desktop becomes kde-foo.desktop).
+func desktopFileID(dir, path string) string {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return filepath.Base(path)
+	}
+	return strings.ReplaceAll(filepath.ToSlash(rel), "/", "-")
+}
+
+// tryExecAvailable reports whether the TryExec binary is installed. An empty
+// value means the key was not set.
+func tryExecAvailable(tryExec string) bool {
+	if tryExec == "" {
+		return true
+	}
+	_, err := exec.LookPath(tryExec)
+	return err == nil
+}
+
+func parseDesktopFile(path string, env desktopEnv) (Application, error) {
+	groups, err := readDesktopGroups(path)
+	if err != nil {
 		return Application{}, err
 	}
+	entry, ok := groups["Desktop Entry"]
+	if !ok {
+		return Application{}, errSkipApplication
+	}
 
-	if appType != "Application" || hidden || noDisplay || name == "" || exec == "" {
+	locales := env.locales
+	name := entry.localized("Name", locales)
+	if name == "" {
+		// Entries carrying only translated names are still listed.
+		name = entry.anyLocalized("Name")
+	}
+	execLine := entry.string("Exec")
+	if entry["Type"] != "Application" || entry.bool("Hidden") ||
+		name == "" || strings.TrimSpace(execLine) == "" {
 		return Application{}, errSkipApplication
 	}
+	if !env.shows(entry.list("OnlyShowIn"), entry.list("NotShowIn")) || !tryExecAvailable(entry.string("TryExec")) {
+		return Application{}, errSkipApplication
+	}
+	if _, err := splitExec(execLine); err != nil {
+		return Application{}, err
+	}
 
+	iconName := entry.string("Icon")
 	iconPath := resolveIcon(iconName, path)
 
 	return Application{
-		Name:     name,
-		Exec:     exec,
-		IconName: iconName,
-		IconPath: iconPath,
-		Path:     path,
+		Name:             name,
+		UntranslatedName: entry.string("Name"),
+		GenericName:      entry.localized("GenericName", locales),
+		Comment:          entry.localized("Comment", locales),
+		Keywords:         mergeLists(entry.localizedList("Keywords", locales), entry.list("Keywords")),
+		Categories:       entry.list("Categories"),
+		Exec:             execLine,
+		IconName:         iconName,
+		IconPath:         iconPath,
+		Path:             path,
+		Terminal:         entry.bool("Terminal"),
+		WorkingDir:       entry.string("Path"),
+		StartupNotify:    entry.bool("StartupNotify"),
+		StartupWMClass:   entry.string("StartupWMClass"),
+		MimeTypes:        entry.list("MimeType"),
+		NoDisplay:        entry.bool("NoDisplay"),
+		Actions:          parseActions(groups, entry.list("Actions"), path, locales),
 	}, nil
 }
 
-func sanitiseExec(raw string) string {
-	fields := strings.Fields(raw)
-	cleaned := make([]string, 0, len(fields))
-	for _, field := range fields {
-		if strings.Contains(field, "%") {
+func parseActions(groups map[string]desktopGroup, ids []string, path string, locales []string) []Action {
+	if len(ids) == 0 {
+		return nil
+	}
+	actions := make([]Action, 0, len(ids))
+	for _, id := range ids {
+		group, ok := groups["Desktop Action "+id]
+		if !ok {
 			continue
 		}
-		cleaned = append(cleaned, field)
+		name := group.localized("Name", locales)
+		execLine := group.string("Exec")
+		if name == "" || strings.TrimSpace(execLine) == "" {
+			continue
+		}
+		if _, err := splitExec(execLine); err != nil {
+			continue
+		}
+		iconName := group.string("Icon")
+		actions = append(actions, Action{
+			ID:       id,
+			Name:     name,
+			Exec:     execLine,
+			IconName: iconName,
+			IconPath: resolveIcon(iconName, path),
+		})
 	}
-	return strings.Join(cleaned, " ")
+	return actions
 }
 
-func desktopDirs() []string {
-	dirs := make([]string, 0, 6)
-
-	if dataHome := os.Getenv("XDG_DATA_HOME"); dataHome != "" {
-		dirs = append(dirs, filepath.Join(dataHome, "applications"))
-	} else if home, err := os.UserHomeDir(); err == nil {
-		dirs = append(dirs, filepath.Join(home, ".local/share/applications"))
-	}
-
-	dataDirsEnv := os.Getenv("XDG_DATA_DIRS")
-	if dataDirsEnv == "" {
-		dataDirsEnv = "/usr/local/share:/usr/share"
-	}
-	for _, dir := range strings.Split(dataDirsEnv, ":") {
-		dir = strings.TrimSpace(dir)
-		if dir == "" {
+// ExpandActions returns one launchable entry per desktop action declared by
+// the given applications. Entries inherit the parent's icon when the action
+// has none and are named "<app>: <action>" so they are searchable by both.
+func ExpandActions(apps []Application) []Application {
+	var expanded []Application
+	for _, app := range apps {
+		if app.NoDisplay {
 			continue
 		}
-		dirs = append(dirs, filepath.Join(dir, "applications"))
+		for _, action := range app.Actions {
+			entry := Application{
+				ID:             app.ID,
+				Name:           app.Name + ": " + action.Name,
+				Exec:           action.Exec,
+				IconName:       action.IconName,
+				IconPath:       action.IconPath,
+				Path:           app.Path,
+				Terminal:       app.Terminal,
+				WorkingDir:     app.WorkingDir,
+				Source:         app.Source,
+				ActionID:       action.ID,
+				StartupNotify:  app.StartupNotify,
+				StartupWMClass: app.StartupWMClass,
+			}
+			if app.UntranslatedName != "" {
+				entry.UntranslatedName = app.UntranslatedName + ": " + action.Name
+			}
+			if entry.IconPath == "" {
+				entry.IconName = app.IconName
+				entry.IconPath = app.IconPath
+			}
+			expanded = append(expanded, entry)
+		}
 	}
-
-	dirs = append(dirs, "/var/lib/snapd/desktop/applications")
-	return dirs
+	return expanded
 }
 
-var (
-	iconIndex     map[string]string
-	iconIndexOnce sync.Once
-)
+// desktopGroup holds the raw key/value pairs of one [Group] in a desktop file.
+// Localized keys are stored verbatim, e.g. "Name[nb_NO]".
+type desktopGroup map[string]string
 
-func resolveIcon(iconValue, desktopPath string) string {
-	if iconValue == "" {
-		return ""
-	}
+func (g desktopGroup) string(key string) string {
+	return unescapeString(g[key])
+}
+
+// list splits a semicolon separated value, honouring the "\;" escape.
+func (g desktopGroup) list(key string) []string {
+	return splitList(g[key])
+}
 
-	if filepath.IsAbs(iconValue) {
-		if fileExists(iconValue) {
-			return iconValue
+// localizedList is the list counterpart of localized.
+func (g desktopGroup) localizedList(key string, locales []string) []string {
+	for _, locale := range locales {
+		if items := splitList(g[key+"["+locale+"]"]); len(items) > 0 {
+			return items
 		}
-		return ""
 	}
+	return g.list(key)
+}
 
-	desktopDir := filepath.Dir(desktopPath)
+func (g desktopGroup) bool(key string) bool {
+	return strings.EqualFold(g[key], "true")
+}
 
-	if candidate := findIconWithExtensions(filepath.Join(desktopDir, iconValue)); candidate != "" {
-		return candidate
+// localized returns the value of key for the first matching locale, falling
+// back to the untranslated key.
+func (g desktopGroup) localized(key string, locales []string) string {
+	for _, locale := range locales {
+		if value, ok := g[key+"["+locale+"]"]; ok && value != "" {
+			return unescapeString(value)
+		}
 	}
+	return g.string(key)
+}
 
-	if strings.Contains(iconValue, string(filepath.Separator)) {
-		if candidate := findIconWithExtensions(filepath.Clean(filepath.Join(desktopDir, iconValue))); candidate != "" {
-			return candidate
+// anyLocalized returns a translation of key for any locale, for entries
+// lacking both the plain key and one for the user's locales. The
+// alphabetically first locale wins so the choice is stable.
+func (g desktopGroup) anyLocalized(key string) string {
+	best := ""
+	for k, value := range g {
+		if strings.HasPrefix(k, key+"[") && strings.HasSuffix(k, "]") && value != "" && (best == "" || k < best) {
+			best = k
 		}
 	}
-
-	index := loadIconIndex()
-	name := iconKey(iconValue)
-	if name == "" {
+	if best == "" {
 		return ""
 	}
-	if path, ok := index[name]; ok {
-		return path
-	}
-	return ""
+	return unescapeString(g[best])
 }
 
-func findIconWithExtensions(base string) string {
-	if fileExists(base) {
-		return base
-	}
-	if filepath.Ext(base) != "" {
-		return ""
-	}
-	for _, ext := range []string{".png", ".svg", ".xpm"} {
-		candidate := base + ext
-		if fileExists(candidate) {
-			return candidate
+// mergeLists concatenates lists, dropping case-insensitive duplicates.
+func mergeLists(lists ...[]string) []string {
+	var merged []string
+	seen := make(map[string]struct{})
+	for _, list := range lists {
+		for _, item := range list {
+			key := strings.ToLower(item)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			merged = append(merged, item)
 		}
 	}
-	return ""
-}
-
-func fileExists(path string) bool {
-	info, err := os.Stat(path)
-	return err == nil && !info.IsDir()
+	return merged
 }
 
-func loadIconIndex() map[string]string {
-	iconIndexOnce.Do(func() {
-		iconIndex = make(map[string]string)
-		for _, dir := range iconDirs() {
-			filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
-				if err != nil {
-					return nil
-				}
-				if entry.IsDir() {
-					return nil
-				}
-				ext := strings.ToLower(filepath.Ext(entry.Name()))
-				if ext != ".png" && ext != ".svg" && ext != ".xpm" {
-					return nil
-				}
-				key := iconKey(entry.Name())
-				if key == "" {
-					return nil
-				}
-				existing, ok := iconIndex[key]
-				if !ok || betterIconCandidate(path, existing) {
-					iconIndex[key] = path
-				}
-				return nil
-			})
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	var (
+		items   []string
+		current strings.Builder
+	)
+	for i := 0; i < len(value); i++ {
+		switch {
+		case value[i] == '\\' && i+1 < len(value) && value[i+1] == ';':
+			current.WriteByte(';')
+			i++
+		case value[i] == ';':
+			if item := strings.TrimSpace(unescapeString(current.String())); item != "" {
+				items = append(items, item)
+			}
+			current.Reset()
+		default:
+			current.WriteByte(value[i])
 		}
-	})
-	return iconIndex
-}
-
-func iconKey(name string) string {
-	if name == "" {
-		return ""
 	}
-	base := name
-	if ext := strings.ToLower(filepath.Ext(base)); ext == ".png" || ext == ".svg" || ext == ".xpm" {
-		base = strings.TrimSuffix(base, filepath.Ext(base))
+	if item := strings.TrimSpace(unescapeString(current.String())); item != "" {
+		items = append(items, item)
 	}
-	base = strings.ToLower(base)
-	return base
+	return items
 }
 
-func betterIconCandidate(newPath, existingPath string) bool {
-	newScore := iconScore(newPath)
-	oldScore := iconScore(existingPath)
-	if newScore == oldScore {
-		return len(newPath) < len(existingPath)
+func readDesktopGroups(path string) (map[string]desktopGroup, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
 	}
-	return newScore > oldScore
+	defer file.Close()
+	return parseDesktopGroups(file)
 }
 
-func iconScore(path string) int {
-	score := 0
-	if strings.Contains(path, string(filepath.Separator)+"apps"+string(filepath.Separator)) {
-		score += 2
+// parseDesktopGroups reads desktop entry groups from r, keyed by group name.
+func parseDesktopGroups(r io.Reader) (map[string]desktopGroup, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), 1024*1024)
+
+	groups := make(map[string]desktopGroup)
+	var current desktopGroup
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			name := line[1 : len(line)-1]
+			if _, ok := groups[name]; ok {
+				// Duplicate groups are invalid; keep the first occurrence.
+				current = nil
+				continue
+			}
+			current = make(desktopGroup)
+			groups[name] = current
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(parts[0])
+		if _, ok := current[key]; ok {
+			continue
+		}
+		current[key] = strings.TrimSpace(parts[1])
 	}
-	switch strings.ToLower(filepath.Ext(path)) {
-	case ".png":
-		score += 2
-	case ".svg":
-		score += 1
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
 	}
-	return score
+	return groups, nil
 }
 
-func iconDirs() []string {
-	dirs := make([]string, 0, 8)
-
-	if dataHome := os.Getenv("XDG_DATA_HOME"); dataHome != "" {
-		dirs = append(dirs,
-			filepath.Join(dataHome, "icons"),
-			filepath.Join(dataHome, "pixmaps"),
-		)
-	} else if home, err := os.UserHomeDir(); err == nil {
-		dirs = append(dirs,
-			filepath.Join(home, ".local/share/icons"),
-			filepath.Join(home, ".local/share/pixmaps"),
-		)
-	}
+func desktopDirs() []string {
+	dirs := make([]string, 0, 6)
 
-	if home, err := os.UserHomeDir(); err == nil {
-		dirs = append(dirs, filepath.Join(home, ".icons"))
+	if dataHome := dataHomeDir(); dataHome != "" {
+		dirs = append(dirs, filepath.Join(dataHome, "applications"))
 	}
 
-	dataDirsEnv := os.Getenv("XDG_DATA_DIRS")
-	if dataDirsEnv == "" {
-		dataDirsEnv = "/usr/local/share:/usr/share"
-	}
-	for _, dir := range strings.Split(dataDirsEnv, ":") {
-		dir = strings.TrimSpace(dir)
-		if dir == "" {
-			continue
-		}
-		dirs = append(dirs,
-			filepath.Join(dir, "icons"),
-			filepath.Join(dir, "pixmaps"),
-		)
+	for _, dir := range xdgDataDirs() {
+		dirs = append(dirs, filepath.Join(dir, "applications"))
 	}
 
-	dirs = append(dirs, "/usr/share/pixmaps")
 	return dirs
 }
-
-// DebugResolveIcon is a helper for diagnostics.
-func DebugResolveIcon(iconName string) string {
-	return resolveIcon(iconName, "")
-}
-
-func DebugResolveIconWithDesktop(iconName, desktopPath string) string {
-	return resolveIcon(iconName, desktopPath)
-}
@@ -7,3 +7,7 @@ import "fmt"
 func listDarwin() ([]Application, error) {
 	return nil, fmt.Errorf("listDarwin is only available on darwin")
 }
+
+func darwinRoots() []string {
+	return nil
+}
@@ -0,0 +1,43 @@
+package applications
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseDesktopFileName(t *testing.T) {
+	env := desktopEnv{locales: []string{"de_DE", "de"}}
+	tests := []struct {
+		name  string
+		names string
+		want  string
+	}{
+		{"plain", "Name=Files\n", "Files"},
+		{"user's locale", "Name=Files\nName[de]=Dateien\nName[fr]=Fichiers\n", "Dateien"},
+		{"more specific locale first", "Name[de]=Dateien\nName[de_DE]=Dateien (DE)\n", "Dateien (DE)"},
+		{"any translation", "Name[nb]=Filer\nName[fr]=Fichiers\n", "Fichiers"},
+		{"empty translations skipped", "Name[fr]=\nName[nb]=Filer\n", "Filer"},
+		{"no name", "GenericName=File Manager\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "files.desktop")
+			content := "[Desktop Entry]\nType=Application\nExec=files\n" + tt.names
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			app, err := parseDesktopFile(path, env)
+			if tt.want == "" {
+				if !errors.Is(err, errSkipApplication) {
+					t.Errorf("parseDesktopFile = %+v, %v; want the entry skipped", app, err)
+				}
+				return
+			}
+			if err != nil || app.Name != tt.want {
+				t.Errorf("parseDesktopFile name = %q, %v; want %q", app.Name, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package applications
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// macInfoPlist holds the Info.plist keys used to describe an app bundle.
+type macInfoPlist struct {
+	Identifier  string
+	DisplayName string
+	BundleName  string
+	Executable  string
+	IconFile    string
+	IconFiles   []string
+	// UIElement is LSUIElement: an agent app with no Dock icon or menu bar.
+	UIElement bool
+	// BackgroundOnly is LSBackgroundOnly: an app with no user interface.
+	BackgroundOnly bool
+}
+
+// parseAppBundle describes the app bundle at bundlePath. The name prefers the
+// localized CFBundleDisplayName for the user's languages, then the
+// unlocalized display and bundle names, then the bundle's file name.
+func parseAppBundle(bundlePath string, languages []string) (Application, error) {
+	info, err := readInfoPlist(bundlePath)
+	localized := localizedBundleStrings(bundlePath, languages)
+	name := firstNonEmpty(
+		localized["CFBundleDisplayName"],
+		localized["CFBundleName"],
+		info.DisplayName,
+		info.BundleName,
+		strings.TrimSuffix(filepath.Base(bundlePath), ".app"),
+	)
+
+	iconPath := resolveMacIcon(bundlePath, info)
+	iconName := ""
+	if iconPath != "" {
+		iconName = filepath.Base(iconPath)
+	}
+
+	return Application{
+		ID:               info.Identifier,
+		Name:             name,
+		UntranslatedName: firstNonEmpty(info.DisplayName, info.BundleName),
+		Exec:             bundlePath,
+		IconName:         iconName,
+		IconPath:         iconPath,
+		Path:             bundlePath,
+		Agent:            info.UIElement,
+		NoDisplay:        info.BackgroundOnly,
+	}, err
+}
+
+func readInfoPlist(bundlePath string) (macInfoPlist, error) {
+	infoPath := filepath.Join(bundlePath, "Contents", "Info.plist")
+	data, err := os.ReadFile(infoPath)
+	if err != nil {
+		return macInfoPlist{}, fmt.Errorf("read info.plist: %w", err)
+	}
+	value, err := parsePlist(data)
+	if err != nil {
+		return macInfoPlist{}, fmt.Errorf("parse %s: %w", infoPath, err)
+	}
+	raw, ok := value.(map[string]any)
+	if !ok {
+		return macInfoPlist{}, fmt.Errorf("parse %s: top level is not a dictionary", infoPath)
+	}
+
+	return macInfoPlist{
+		Identifier:     plistString(raw, "CFBundleIdentifier"),
+		DisplayName:    plistString(raw, "CFBundleDisplayName"),
+		BundleName:     plistString(raw, "CFBundleName"),
+		Executable:     plistString(raw, "CFBundleExecutable"),
+		IconFile:       plistString(raw, "CFBundleIconFile"),
+		IconFiles:      plistStrings(raw, "CFBundleIconFiles"),
+		UIElement:      plistBool(raw, "LSUIElement"),
+		BackgroundOnly: plistBool(raw, "LSBackgroundOnly"),
+	}, nil
+}
+
+// localizedBundleStrings returns the InfoPlist.strings entries of the first
+// .lproj directory matching the user's languages.
+func localizedBundleStrings(bundlePath string, languages []string) map[string]string {
+	resources := filepath.Join(bundlePath, "Contents", "Resources")
+	for _, dir := range lprojCandidates(languages) {
+		data, err := os.ReadFile(filepath.Join(resources, dir+".lproj", "InfoPlist.strings"))
+		if err != nil {
+			continue
+		}
+		if values, err := parseStringsFile(data); err == nil && len(values) > 0 {
+			return values
+		}
+	}
+	return nil
+}
+
+// lprojCandidates expands languages such as "de-DE" or "pt_BR.UTF-8" into
+// the .lproj directory names to try, most specific first.
+func lprojCandidates(languages []string) []string {
+	var dirs []string
+	add := func(dir string) {
+		for _, existing := range dirs {
+			if existing == dir {
+				return
+			}
+		}
+		dirs = append(dirs, dir)
+	}
+	for _, language := range languages {
+		language, _, _ = strings.Cut(language, ".")
+		language, _, _ = strings.Cut(language, "@")
+		if language == "" || language == "C" || language == "POSIX" {
+			continue
+		}
+		add(language)
+		add(strings.ReplaceAll(language, "_", "-"))
+		add(strings.ReplaceAll(language, "-", "_"))
+		base, _, _ := strings.Cut(strings.ReplaceAll(language, "_", "-"), "-")
+		add(base)
+	}
+	return dirs
+}
+
+func resolveMacIcon(bundlePath string, info macInfoPlist) string {
+	resources := filepath.Join(bundlePath, "Contents", "Resources")
+
+	candidates := make([]string, 0, len(info.IconFiles)+1)
+	if strings.TrimSpace(info.IconFile) != "" {
+		candidates = append(candidates, strings.TrimSpace(info.IconFile))
+	}
+	for _, name := range info.IconFiles {
+		trimmed := strings.TrimSpace(name)
+		if trimmed != "" {
+			candidates = append(candidates, trimmed)
+		}
+	}
+
+	for _, name := range candidates {
+		if filepath.Ext(name) == "" {
+			if candidate := filepath.Join(resources, name+".icns"); fileExists(candidate) {
+				return candidate
+			}
+		}
+		candidate := filepath.Join(resources, name)
+		if fileExists(candidate) {
+			return candidate
+		}
+	}
+
+	entries, err := os.ReadDir(resources)
+	if err != nil {
+		return ""
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if strings.EqualFold(filepath.Ext(entry.Name()), ".icns") {
+			candidate := filepath.Join(resources, entry.Name())
+			if fileExists(candidate) {
+				return candidate
+			}
+		}
+	}
+	return ""
+}
+
+// macPreferredLanguages returns the user's languages from the AppleLanguages
+// preference, falling back to the POSIX locale and then English.
+func macPreferredLanguages() []string {
+	var languages []string
+	if home, err := os.UserHomeDir(); err == nil {
+		data, err := os.ReadFile(filepath.Join(home, "Library", "Preferences", ".GlobalPreferences.plist"))
+		if err == nil {
+			if value, err := parsePlist(data); err == nil {
+				if prefs, ok := value.(map[string]any); ok {
+					languages = plistStrings(prefs, "AppleLanguages")
+				}
+			}
+		}
+	}
+	languages = append(languages, messageLocales()...)
+	return append(languages, "en", "English", "Base")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if trimmed := strings.TrimSpace(v); trimmed != "" {
+			return trimmed
+		}
+	}
+	return ""
+}
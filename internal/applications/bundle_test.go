@@ -0,0 +1,96 @@
+package applications
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseAppBundle(t *testing.T) {
+	bundles := filepath.Join("testdata", "bundles")
+	tests := []struct {
+		bundle    string
+		languages []string
+		want      Application
+	}{
+		{
+			// Binary Info.plist, LSUIElement and a UTF-16 InfoPlist.strings.
+			bundle:    "Agent.app",
+			languages: []string{"de_DE.UTF-8", "en"},
+			want: Application{
+				ID:               "com.example.Agent",
+				Name:             "Agentenwerkzeug",
+				UntranslatedName: "Agent Tool",
+				IconName:         "AppIcon.icns",
+				Agent:            true,
+			},
+		},
+		{
+			bundle:    "Agent.app",
+			languages: []string{"fr", "en"},
+			want: Application{
+				ID:               "com.example.Agent",
+				Name:             "Agent Tool",
+				UntranslatedName: "Agent Tool",
+				IconName:         "AppIcon.icns",
+				Agent:            true,
+			},
+		},
+		{
+			// XML Info.plist with a string boolean and no icon key.
+			bundle:    "Helper.app",
+			languages: []string{"en"},
+			want: Application{
+				ID:               "com.example.Helper",
+				Name:             "Helper",
+				UntranslatedName: "Helper",
+				IconName:         "Helper.icns",
+				NoDisplay:        true,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.bundle, func(t *testing.T) {
+			path := filepath.Join(bundles, tt.bundle)
+			got, err := parseAppBundle(path, tt.languages)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.want.Exec = path
+			tt.want.Path = path
+			tt.want.IconPath = filepath.Join(path, "Contents", "Resources", tt.want.IconName)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseAppBundle(%s) =\n%+v\nwant\n%+v", tt.bundle, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAppBundleWithoutInfoPlist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Bare.app")
+	app, err := parseAppBundle(path, nil)
+	if err == nil {
+		t.Error("parseAppBundle did not report the missing Info.plist")
+	}
+	if app.Name != "Bare" {
+		t.Errorf("Name = %q, want the bundle's file name", app.Name)
+	}
+}
+
+func TestLprojCandidates(t *testing.T) {
+	tests := []struct {
+		languages []string
+		want      []string
+	}{
+		{[]string{"de-DE"}, []string{"de-DE", "de_DE", "de"}},
+		{[]string{"pt_BR.UTF-8"}, []string{"pt_BR", "pt-BR", "pt"}},
+		{[]string{"sr@latin"}, []string{"sr"}},
+		{[]string{"C", "POSIX", ""}, nil},
+		{[]string{"en-US", "en", "English"}, []string{"en-US", "en_US", "en", "English"}},
+	}
+	for _, tt := range tests {
+		if got := lprojCandidates(tt.languages); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("lprojCandidates(%q) = %q, want %q", tt.languages, got, tt.want)
+		}
+	}
+}
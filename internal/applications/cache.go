@@ -0,0 +1,204 @@
+package applications
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// cacheVersion is bumped whenever the cached types change shape so stale
+// files from older builds are ignored rather than misread.
+const cacheVersion = 4
+
+// appCache is the on-disk snapshot written by the Watcher.
+type appCache struct {
+	Version int
+	// Env identifies the locale, desktop and icon theme the applications were
+	// parsed for; a different session must not reuse them.
+	Env    string
+	Stamps map[string]int64
+	Apps   []Application
+}
+
+type iconCache struct {
+	Version  int
+	BaseDirs []string
+	Themes   map[string]*iconTheme
+}
+
+// CachedList returns the applications from the last scan without touching the
+// application directories. ok is false when no usable cache exists. The
+// snapshot may be stale; Watch rescans in the background and reports changes.
+func CachedList() ([]Application, bool) {
+	cache, ok := readAppCache()
+	if !ok {
+		return nil, false
+	}
+	return cache.Apps, true
+}
+
+// readAppCache returns the cached snapshot if it was written for this
+// session. Whether it still matches the directories it was scanned from is
+// up to the caller to check.
+func readAppCache() (appCache, bool) {
+	var cache appCache
+	if err := readCacheFile("applications.gob", &cache); err != nil {
+		return appCache{}, false
+	}
+	return cache, cache.Version == cacheVersion && cache.Env == cacheEnvKey()
+}
+
+// sameApplications reports whether a and b are equal as far as the cache
+// can tell; gob does not distinguish nil from empty slices.
+func sameApplications(a, b []Application) bool {
+	var encodedA, encodedB bytes.Buffer
+	if gob.NewEncoder(&encodedA).Encode(a) != nil || gob.NewEncoder(&encodedB).Encode(b) != nil {
+		return false
+	}
+	return bytes.Equal(encodedA.Bytes(), encodedB.Bytes())
+}
+
+func saveAppCache(apps []Application, dirs []string) {
+	stamps := make(map[string]int64, len(dirs))
+	for _, dir := range dirs {
+		stamps[dir] = pathStamp(dir)
+	}
+	writeCacheFile("applications.gob", appCache{
+		Version: cacheVersion,
+		Env:     cacheEnvKey(),
+		Stamps:  stamps,
+		Apps:    apps,
+	})
+}
+
+func loadIconCache(baseDirs []string) map[string]*iconTheme {
+	var cache iconCache
+	if err := readCacheFile("icons.gob", &cache); err != nil {
+		return nil
+	}
+	if cache.Version != cacheVersion || !slices.Equal(cache.BaseDirs, baseDirs) {
+		return nil
+	}
+	return cache.Themes
+}
+
+// flushIconCache writes the themes indexed so far when any of them had to be
+// read from disk.
+func flushIconCache() {
+	r := defaultIconResolver()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.dirty {
+		return
+	}
+	themes := make(map[string]*iconTheme, len(r.themes))
+	for name, theme := range r.themes {
+		if theme != nil {
+			themes[name] = theme
+		}
+	}
+	writeCacheFile("icons.gob", iconCache{
+		Version:  cacheVersion,
+		BaseDirs: r.baseDirs,
+		Themes:   themes,
+	})
+	r.dirty = false
+}
+
+// cacheEnvKey identifies the session the cached applications were parsed for.
+// CachedList runs on the UI goroutine, so it names the icon theme rather than
+// building the icon resolver.
+func cacheEnvKey() string {
+	env := currentDesktopEnv()
+	roots, _ := desktopRoots()
+	for _, source := range sources {
+		if lister, ok := source.(Lister); ok {
+			roots = append(roots, lister.Dirs()...)
+		}
+	}
+	return strings.Join([]string{
+		strings.Join(env.locales, ","),
+		strings.Join(env.desktops, ","),
+		iconThemeName(),
+		strings.Join(roots, ":"),
+	}, "|")
+}
+
+// cacheDir returns $XDG_CACHE_HOME/launcher (or the platform equivalent).
+func cacheDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "launcher"), nil
+}
+
+func readCacheFile(name string, v any) error {
+	dir, err := cacheDir()
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return gob.NewDecoder(file).Decode(v)
+}
+
+// writeCacheFile replaces the cache file atomically. Failures are ignored: the
+// cache only speeds up start-up and is rebuilt on the next scan.
+func writeCacheFile(name string, v any) {
+	dir, err := cacheDir()
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(dir, name+".*")
+	if err != nil {
+		return
+	}
+	if err := gob.NewEncoder(tmp).Encode(v); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, name)); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// pathStamp returns the modification time of path in nanoseconds, or 0 when it
+// does not exist so that creating it later invalidates the cache.
+func pathStamp(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0
+		}
+		return -1
+	}
+	return info.ModTime().UnixNano()
+}
+
+func stampsValid(stamps map[string]int64) bool {
+	if len(stamps) == 0 {
+		return false
+	}
+	for path, stamp := range stamps {
+		if pathStamp(path) != stamp {
+			return false
+		}
+	}
+	return true
+}
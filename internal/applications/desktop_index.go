@@ -0,0 +1,187 @@
+package applications
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// desktopIndex tracks every .desktop file found under the application
+// directories, grouped by desktop file ID. Files are parsed lazily, and only
+// the highest-priority file for each ID is ever parsed, so the index can be
+// updated one file at a time as the directories change.
+type desktopIndex struct {
+	roots []string
+	// sources names the Source each root belongs to.
+	sources []string
+	env     desktopEnv
+	// files maps a desktop file ID to its candidates ordered by root, so the
+	// first element is the one that shadows the rest.
+	files map[string][]*desktopFile
+	dirs  map[string]struct{}
+}
+
+type desktopFile struct {
+	root   int
+	path   string
+	parsed bool
+	app    Application
+	err    error
+}
+
+func newDesktopIndex(roots, sources []string, env desktopEnv) *desktopIndex {
+	return &desktopIndex{
+		roots:   roots,
+		sources: sources,
+		env:     env,
+		files:   make(map[string][]*desktopFile),
+		dirs:    make(map[string]struct{}),
+	}
+}
+
+// scan walks every root and records the files found.
+func (x *desktopIndex) scan() []error {
+	var errs []error
+	for root := range x.roots {
+		errs = append(errs, x.scanDir(root, x.roots[root])...)
+	}
+	return errs
+}
+
+// scanDir walks dir, which lies inside the root with the given index.
+func (x *desktopIndex) scanDir(root int, dir string) []error {
+	var errs []error
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			errs = append(errs, fmt.Errorf("read dir %s: %w", path, err))
+			if entry != nil && entry.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			x.dirs[path] = struct{}{}
+			return nil
+		}
+		x.add(root, path)
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("walk %s: %w", dir, err))
+	}
+	return errs
+}
+
+// add records or refreshes the file at path. Non-desktop files are ignored.
+func (x *desktopIndex) add(root int, path string) {
+	if !strings.HasSuffix(path, ".desktop") {
+		return
+	}
+	id := desktopFileID(x.roots[root], path)
+	candidates := x.files[id]
+	for _, file := range candidates {
+		if file.path == path {
+			file.parsed = false
+			return
+		}
+	}
+	candidates = append(candidates, &desktopFile{root: root, path: path})
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].root < candidates[j].root
+	})
+	x.files[id] = candidates
+}
+
+// remove forgets path, or every file below it when path was a directory.
+func (x *desktopIndex) remove(path string) {
+	prefix := path + string(filepath.Separator)
+	for dir := range x.dirs {
+		if dir == path || strings.HasPrefix(dir, prefix) {
+			delete(x.dirs, dir)
+		}
+	}
+	for id, candidates := range x.files {
+		kept := candidates[:0]
+		for _, file := range candidates {
+			if file.path != path && !strings.HasPrefix(file.path, prefix) {
+				kept = append(kept, file)
+			}
+		}
+		if len(kept) == 0 {
+			delete(x.files, id)
+		} else {
+			x.files[id] = kept
+		}
+	}
+}
+
+// rootFor returns the index of the root containing path, or -1.
+func (x *desktopIndex) rootFor(path string) int {
+	for i, root := range x.roots {
+		if path == root || strings.HasPrefix(path, root+string(filepath.Separator)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// applications parses whatever winning files have not been parsed yet and
+// returns the visible applications sorted by name.
+func (x *desktopIndex) applications() ([]Application, []error) {
+	apps := make([]Application, 0, len(x.files))
+	var errs []error
+	for id, candidates := range x.files {
+		// Earlier directories take precedence, so the first file seen for an
+		// ID shadows the rest even when it is hidden or filtered out.
+		file := candidates[0]
+		if !file.parsed {
+			file.app, file.err = parseDesktopFile(file.path, x.env)
+			file.app.ID = id
+			file.app.Source = x.sources[file.root]
+			file.parsed = true
+		}
+		if file.err != nil {
+			if !errors.Is(file.err, errSkipApplication) {
+				errs = append(errs, fmt.Errorf("parse %s: %w", file.path, file.err))
+			}
+			continue
+		}
+		apps = append(apps, file.app)
+	}
+	sortApplications(apps)
+	return apps, errs
+}
+
+// refreshIcons re-resolves the icons of every parsed application, used after
+// the icon directories changed.
+func (x *desktopIndex) refreshIcons() {
+	for _, candidates := range x.files {
+		file := candidates[0]
+		if !file.parsed || file.err != nil {
+			continue
+		}
+		file.app.IconPath = resolveIcon(file.app.IconName, file.path)
+		// Copy the actions since earlier snapshots share the slice.
+		actions := append([]Action(nil), file.app.Actions...)
+		for i := range actions {
+			actions[i].IconPath = resolveIcon(actions[i].IconName, file.path)
+		}
+		file.app.Actions = actions
+	}
+}
+
+func (x *desktopIndex) visitedDirs() []string {
+	dirs := make([]string, 0, len(x.roots)+len(x.dirs))
+	dirs = append(dirs, x.roots...)
+	for dir := range x.dirs {
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
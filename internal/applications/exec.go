@@ -0,0 +1,245 @@
+package applications
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// execToken is a single argument of an Exec line after quoting has been
+// removed. Quoted tokens never expand to more or fewer than one argument.
+type execToken struct {
+	text   string
+	quoted bool
+}
+
+// ExpandExec turns the application's Exec key into an argv slice following the
+// Desktop Entry Specification. Targets are the files or URLs the application is
+// asked to open and are substituted for %f, %F, %u and %U.
+func ExpandExec(app Application, targets ...string) ([]string, error) {
+	tokens, err := splitExec(app.Exec)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(targets))
+	for _, target := range targets {
+		if path, ok := localPath(target); ok {
+			files = append(files, path)
+		}
+	}
+
+	argv := make([]string, 0, len(tokens)+len(targets))
+	for _, tok := range tokens {
+		if !tok.quoted && len(tok.text) == 2 && tok.text[0] == '%' {
+			switch tok.text[1] {
+			case 'f':
+				argv = append(argv, firstOf(files)...)
+				continue
+			case 'F':
+				argv = append(argv, files...)
+				continue
+			case 'u':
+				argv = append(argv, firstOf(targets)...)
+				continue
+			case 'U':
+				argv = append(argv, targets...)
+				continue
+			case 'i':
+				if app.IconName != "" {
+					argv = append(argv, "--icon", app.IconName)
+				}
+				continue
+			case 'd', 'D', 'n', 'N', 'v', 'm':
+				continue
+			}
+		}
+		expanded, err := expandFieldCodes(tok.text, app, files, targets)
+		if err != nil {
+			return nil, err
+		}
+		argv = append(argv, expanded)
+	}
+
+	if len(argv) == 0 {
+		return nil, fmt.Errorf("empty exec line")
+	}
+	return argv, nil
+}
+
+// splitExec tokenizes an Exec value. Arguments are separated by unquoted
+// whitespace; inside double quotes a backslash escapes ", `, $ and \.
+// Outside quotes a backslash escapes the following character.
+func splitExec(raw string) ([]execToken, error) {
+	var (
+		tokens  []execToken
+		current strings.Builder
+		started bool
+		quoted  bool
+		inQuote bool
+	)
+
+	flush := func() {
+		if started {
+			tokens = append(tokens, execToken{text: current.String(), quoted: quoted})
+		}
+		current.Reset()
+		started = false
+		quoted = false
+	}
+
+	runes := []rune(raw)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case inQuote && r == '"':
+			inQuote = false
+		case inQuote && r == '\\':
+			if i+1 < len(runes) && strings.ContainsRune("\"`$\\", runes[i+1]) {
+				i++
+				current.WriteRune(runes[i])
+			} else {
+				current.WriteRune(r)
+			}
+		case inQuote:
+			current.WriteRune(r)
+		case r == '"':
+			inQuote = true
+			started = true
+			quoted = true
+		case r == ' ' || r == '\t' || r == '\n':
+			flush()
+		case r == '\\':
+			if i+1 >= len(runes) {
+				return nil, fmt.Errorf("trailing backslash in exec %q", raw)
+			}
+			i++
+			current.WriteRune(runes[i])
+			started = true
+		default:
+			current.WriteRune(r)
+			started = true
+		}
+	}
+	if inQuote {
+		return nil, fmt.Errorf("unterminated quote in exec %q", raw)
+	}
+	flush()
+	return tokens, nil
+}
+
+// joinExec is the inverse of splitExec: it quotes every argument that needs it
+// so the result tokenizes back to the same arguments.
+func joinExec(tokens []execToken) string {
+	parts := make([]string, 0, len(tokens))
+	for _, tok := range tokens {
+		parts = append(parts, quoteExecArg(tok))
+	}
+	return strings.Join(parts, " ")
+}
+
+func quoteExecArg(tok execToken) string {
+	if !tok.quoted && tok.text != "" && !strings.ContainsAny(tok.text, " \t\n\"'\\><~|&;$*?#()`") {
+		return tok.text
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range tok.text {
+		if strings.ContainsRune("\"`$\\", r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// expandFieldCodes replaces field codes embedded inside a larger argument.
+// Codes that expand to lists are dropped since they cannot be represented
+// within a single argument.
+func expandFieldCodes(arg string, app Application, files, targets []string) (string, error) {
+	if !strings.ContainsRune(arg, '%') {
+		return arg, nil
+	}
+	var b strings.Builder
+	runes := []rune(arg)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '%' {
+			b.WriteRune(runes[i])
+			continue
+		}
+		if i+1 >= len(runes) {
+			return "", fmt.Errorf("dangling %% in exec argument %q", arg)
+		}
+		i++
+		switch runes[i] {
+		case '%':
+			b.WriteRune('%')
+		case 'f':
+			b.WriteString(strings.Join(firstOf(files), ""))
+		case 'u':
+			b.WriteString(strings.Join(firstOf(targets), ""))
+		case 'c':
+			b.WriteString(app.Name)
+		case 'k':
+			b.WriteString(app.Path)
+		case 'i':
+			b.WriteString(app.IconName)
+		case 'F', 'U', 'd', 'D', 'n', 'N', 'v', 'm':
+		default:
+			return "", fmt.Errorf("invalid field code %%%c in exec argument %q", runes[i], arg)
+		}
+	}
+	return b.String(), nil
+}
+
+// unescapeString applies the escape rules for values of type string: \s, \n,
+// \t, \r and \\. Unknown sequences are kept verbatim.
+func unescapeString(value string) string {
+	if !strings.ContainsRune(value, '\\') {
+		return value
+	}
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c != '\\' || i+1 >= len(value) {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch value[i] {
+		case 's':
+			b.WriteByte(' ')
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case '\\':
+			b.WriteByte('\\')
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(value[i])
+		}
+	}
+	return b.String()
+}
+
+func localPath(target string) (string, bool) {
+	if !strings.Contains(target, "://") {
+		return target, target != ""
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme != "file" {
+		return "", false
+	}
+	return u.Path, u.Path != ""
+}
+
+func firstOf(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	return values[:1]
+}
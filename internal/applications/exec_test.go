@@ -0,0 +1,102 @@
+package applications
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandExec(t *testing.T) {
+	app := Application{
+		Name:     "Text Editor",
+		Path:     "/usr/share/applications/editor.desktop",
+		IconName: "accessories-text-editor",
+	}
+	tests := []struct {
+		name    string
+		exec    string // as written in the desktop file
+		targets []string
+		want    []string
+	}{
+		{"plain", "editor --new-window", nil, []string{"editor", "--new-window"}},
+		{"double quotes", `"/opt/My Editor/editor" "--title=a b"`, nil, []string{"/opt/My Editor/editor", "--title=a b"}},
+		{"quoted escapes", `sh -c "echo \\"hi\\" \\` + "`" + `x\\` + "`" + `"`, nil, []string{"sh", "-c", "echo \"hi\" `x`"}},
+		{"literal dollar", `sh -c "echo \\$HOME"`, nil, []string{"sh", "-c", "echo $HOME"}},
+		{"literal backslash", `sh -c "printf a\\\\b"`, nil, []string{"sh", "-c", `printf a\b`}},
+		{"unquoted backslash", `editor a\\ b`, nil, []string{"editor", "a b"}},
+		{"percent", "printf 100%%", nil, []string{"printf", "100%"}},
+		{"f without targets", "editor %f", nil, []string{"editor"}},
+		{"f with one target", "editor %f", []string{"/tmp/a.txt"}, []string{"editor", "/tmp/a.txt"}},
+		{"f with many targets", "editor %f", []string{"/tmp/a.txt", "/tmp/b.txt"}, []string{"editor", "/tmp/a.txt"}},
+		{"F without targets", "editor %F", nil, []string{"editor"}},
+		{"F with many targets", "editor %F", []string{"/tmp/a.txt", "file:///tmp/b.txt"}, []string{"editor", "/tmp/a.txt", "/tmp/b.txt"}},
+		{"F skips remote URLs", "editor %F", []string{"https://example.com/", "/tmp/a.txt"}, []string{"editor", "/tmp/a.txt"}},
+		{"u without targets", "browser %u", nil, []string{"browser"}},
+		{"u with many targets", "browser %u", []string{"https://a.example/", "https://b.example/"}, []string{"browser", "https://a.example/"}},
+		{"U with many targets", "browser %U", []string{"https://a.example/", "/tmp/a.txt"}, []string{"browser", "https://a.example/", "/tmp/a.txt"}},
+		{"quoted U stays one argument", `browser "%U"`, []string{"https://a.example/", "/tmp/a.txt"}, []string{"browser", ""}},
+		{"icon", "editor %i", nil, []string{"editor", "--icon", "accessories-text-editor"}},
+		{"name", "editor --class=%c", nil, []string{"editor", "--class=Text Editor"}},
+		{"desktop file", "editor --desktop-file %k", nil, []string{"editor", "--desktop-file", "/usr/share/applications/editor.desktop"}},
+		{"embedded u", "editor --open=%u", []string{"/tmp/a.txt"}, []string{"editor", "--open=/tmp/a.txt"}},
+		{"deprecated codes", "editor %d %D %n %N %v %m", nil, []string{"editor"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := app
+			a.Exec = unescapeString(tt.exec)
+			got, err := ExpandExec(a, tt.targets...)
+			if err != nil {
+				t.Fatalf("ExpandExec(%q) error: %v", tt.exec, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExpandExec(%q) = %q, want %q", tt.exec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandExecIconWithoutName(t *testing.T) {
+	got, err := ExpandExec(Application{Exec: "editor %i"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"editor"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ExpandExec = %q, want %q", got, want)
+	}
+}
+
+func TestExpandExecErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		exec string
+	}{
+		{"invalid field code", "editor --x=%z"},
+		{"dangling percent", "editor 100%"},
+		{"unterminated quote", `editor "a b`},
+		{"trailing backslash", `editor \`},
+		{"empty", "   "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := ExpandExec(Application{Exec: tt.exec}); err == nil {
+				t.Errorf("ExpandExec(%q) = %q, want an error", tt.exec, got)
+			}
+		})
+	}
+}
+
+func TestJoinExecRoundTrip(t *testing.T) {
+	tokens := []execToken{
+		{text: "/opt/My Editor/editor"},
+		{text: `say "$HOME" \ ` + "`x`", quoted: true},
+		{text: "%U"},
+	}
+	got, err := splitExec(joinExec(tokens))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens[0].quoted = true // joinExec quotes arguments containing spaces
+	if !reflect.DeepEqual(got, tokens) {
+		t.Errorf("splitExec(joinExec) = %+v, want %+v", got, tokens)
+	}
+}
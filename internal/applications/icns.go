@@ -0,0 +1,203 @@
+package applications
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// icnsEntry is one image stored in an .icns file.
+type icnsEntry struct {
+	kind string
+	data []byte
+}
+
+// Legacy RGB icon types with the size of their images and the type of the
+// matching 8-bit mask.
+var icnsRGBTypes = map[string]struct {
+	size int
+	mask string
+}{
+	"is32": {16, "s8mk"},
+	"il32": {32, "l8mk"},
+	"ih32": {48, "h8mk"},
+	"it32": {128, "t8mk"},
+}
+
+// ARGB icon types, stored as PackBits-compressed channel planes.
+var icnsARGBTypes = map[string]int{
+	"ic04": 16,
+	"ic05": 32,
+}
+
+// decodeICNS returns the image in an Apple icon file best suited to size:
+// the smallest at least that large, or else the largest. PNG, ARGB and
+// legacy RGB entries are supported; JPEG 2000 entries are skipped.
+func decodeICNS(data []byte, size int) (image.Image, error) {
+	entries, err := readICNSEntries(data)
+	if err != nil {
+		return nil, err
+	}
+	masks := make(map[string][]byte)
+	for _, entry := range entries {
+		masks[entry.kind] = entry.data
+	}
+
+	type candidate struct {
+		size   int
+		decode func() (image.Image, error)
+	}
+	var candidates []candidate
+	for _, entry := range entries {
+		entry := entry
+		switch {
+		case bytes.HasPrefix(entry.data, []byte("\x89PNG\r\n\x1a\n")):
+			config, err := png.DecodeConfig(bytes.NewReader(entry.data))
+			if err != nil {
+				continue
+			}
+			candidates = append(candidates, candidate{config.Width, func() (image.Image, error) {
+				return png.Decode(bytes.NewReader(entry.data))
+			}})
+		case icnsARGBTypes[entry.kind] > 0:
+			n := icnsARGBTypes[entry.kind]
+			candidates = append(candidates, candidate{n, func() (image.Image, error) {
+				return decodeICNSARGB(entry.data, n)
+			}})
+		case icnsRGBTypes[entry.kind].size > 0:
+			info := icnsRGBTypes[entry.kind]
+			candidates = append(candidates, candidate{info.size, func() (image.Image, error) {
+				return decodeICNSRGB(entry.kind, entry.data, masks[info.mask], info.size)
+			}})
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, errors.New("icns: no supported images")
+	}
+
+	best := -1
+	for i, c := range candidates {
+		switch {
+		case best < 0:
+			best = i
+		case c.size >= size && (candidates[best].size < size || c.size < candidates[best].size):
+			best = i
+		case candidates[best].size < size && c.size > candidates[best].size:
+			best = i
+		}
+	}
+	img, err := candidates[best].decode()
+	if err != nil {
+		return nil, fmt.Errorf("icns: %w", err)
+	}
+	return img, nil
+}
+
+func readICNSEntries(data []byte) ([]icnsEntry, error) {
+	if len(data) < 8 || string(data[:4]) != "icns" {
+		return nil, errors.New("icns: bad magic")
+	}
+	total := int(binary.BigEndian.Uint32(data[4:8]))
+	if total > len(data) {
+		total = len(data)
+	}
+	var entries []icnsEntry
+	for offset := 8; offset+8 <= total; {
+		length := int(binary.BigEndian.Uint32(data[offset+4:]))
+		if length < 8 || offset+length > total {
+			return entries, errors.New("icns: truncated entry")
+		}
+		entries = append(entries, icnsEntry{
+			kind: string(data[offset : offset+4]),
+			data: data[offset+8 : offset+length],
+		})
+		offset += length
+	}
+	return entries, nil
+}
+
+func decodeICNSARGB(data []byte, size int) (image.Image, error) {
+	if !bytes.HasPrefix(data, []byte("ARGB")) {
+		// Entries of these types may also hold a PNG, handled by the caller.
+		return nil, errors.New("unsupported ARGB entry")
+	}
+	planes, err := unpackICNS(data[4:], size*size*4)
+	if err != nil {
+		return nil, err
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	n := size * size
+	for i := 0; i < n; i++ {
+		img.Pix[i*4] = planes[n+i]
+		img.Pix[i*4+1] = planes[2*n+i]
+		img.Pix[i*4+2] = planes[3*n+i]
+		img.Pix[i*4+3] = planes[i]
+	}
+	return img, nil
+}
+
+func decodeICNSRGB(kind string, data, mask []byte, size int) (image.Image, error) {
+	n := size * size
+	if kind == "it32" && len(data) >= 4 {
+		// it32 data starts with four zero bytes.
+		data = data[4:]
+	}
+	var planes []byte
+	if len(data) == n*3 {
+		// Small icons may be stored uncompressed as RGB triplets.
+		planes = make([]byte, n*3)
+		for i := 0; i < n; i++ {
+			planes[i], planes[n+i], planes[2*n+i] = data[i*3], data[i*3+1], data[i*3+2]
+		}
+	} else {
+		var err error
+		planes, err = unpackICNS(data, n*3)
+		if err != nil {
+			return nil, err
+		}
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	for i := 0; i < n; i++ {
+		alpha := uint8(0xff)
+		if len(mask) == n {
+			alpha = mask[i]
+		}
+		img.SetNRGBA(i%size, i/size, color.NRGBA{R: planes[i], G: planes[n+i], B: planes[2*n+i], A: alpha})
+	}
+	return img, nil
+}
+
+// unpackICNS expands Apple's PackBits variant: a header byte below 0x80 is
+// followed by header+1 literal bytes, otherwise the next byte repeats
+// header-125 times.
+func unpackICNS(data []byte, want int) ([]byte, error) {
+	out := make([]byte, 0, want)
+	for i := 0; i < len(data) && len(out) < want; {
+		header := int(data[i])
+		i++
+		if header < 0x80 {
+			count := header + 1
+			if i+count > len(data) {
+				return nil, errors.New("truncated literal run")
+			}
+			out = append(out, data[i:i+count]...)
+			i += count
+			continue
+		}
+		if i >= len(data) {
+			return nil, errors.New("truncated repeat run")
+		}
+		for j := 0; j < header-125; j++ {
+			out = append(out, data[i])
+		}
+		i++
+	}
+	if len(out) < want {
+		return nil, errors.New("short image data")
+	}
+	return out[:want], nil
+}
@@ -0,0 +1,128 @@
+package applications
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readIconFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "icons", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func nrgbaAt(img image.Image, x, y int) color.NRGBA {
+	return color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+}
+
+func TestDecodeICNS(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		size   int
+		width  int
+		pixels map[image.Point]color.NRGBA
+	}{
+		{
+			// is32 with its s8mk mask, in PackBits runs.
+			name:  "legacy RGB",
+			file:  "legacy.icns",
+			size:  16,
+			width: 16,
+			pixels: map[image.Point]color.NRGBA{
+				{0, 0}: {255, 255, 255, 255},
+				{1, 0}: {255, 0, 0, 0},
+				{5, 5}: {255, 0, 0, 255},
+			},
+		},
+		{
+			name:  "ARGB",
+			file:  "argb.icns",
+			size:  16,
+			width: 16,
+			pixels: map[image.Point]color.NRGBA{
+				{0, 0}: {0, 0, 255, 255},
+				{5, 5}: {0, 255, 0, 128},
+			},
+		},
+		{
+			name:   "exact size",
+			file:   "multi.icns",
+			size:   16,
+			width:  16,
+			pixels: map[image.Point]color.NRGBA{{5, 5}: {255, 0, 0, 255}},
+		},
+		{
+			name:   "smallest larger image",
+			file:   "multi.icns",
+			size:   48,
+			width:  64,
+			pixels: map[image.Point]color.NRGBA{{5, 5}: {0, 0, 255, 255}},
+		},
+		{
+			name:   "largest when none is large enough",
+			file:   "multi.icns",
+			size:   512,
+			width:  256,
+			pixels: map[image.Point]color.NRGBA{{5, 5}: {255, 0, 255, 255}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := decodeICNS(readIconFixture(t, tt.file), tt.size)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := img.Bounds().Dx(); got != tt.width {
+				t.Errorf("width = %d, want %d", got, tt.width)
+			}
+			for pt, want := range tt.pixels {
+				if got := nrgbaAt(img, pt.X, pt.Y); got != want {
+					t.Errorf("pixel %v = %v, want %v", pt, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeICNSErrors(t *testing.T) {
+	legacy := readIconFixture(t, "legacy.icns")
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"bad magic", []byte("icnx\x00\x00\x00\x08")},
+		{"no images", []byte("icns\x00\x00\x00\x08")},
+		{"truncated entry", legacy[:20]},
+		{"only a mask", []byte("icns\x00\x00\x00\x14s8mk\x00\x00\x00\x0c\xff\xff\xff\xff")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeICNS(tt.data, 16); err == nil {
+				t.Error("decodeICNS succeeded")
+			}
+		})
+	}
+}
+
+func TestUnpackICNS(t *testing.T) {
+	// A literal run of two bytes, then 0x7f repeated three times.
+	got, err := unpackICNS([]byte{0x01, 'a', 'b', 0x80, 0x7f}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "ab\x7f\x7f\x7f" {
+		t.Errorf("unpackICNS = %q", got)
+	}
+	for _, data := range [][]byte{{0x05, 'a'}, {0x80}, {0x80, 'a'}} {
+		if _, err := unpackICNS(data, 5); err == nil {
+			t.Errorf("unpackICNS(%q) succeeded", data)
+		}
+	}
+}
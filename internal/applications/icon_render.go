@@ -0,0 +1,120 @@
+package applications
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+// renderedIconLimit bounds the size of an icon file decoded in-process.
+const renderedIconLimit = 8 << 20
+
+// RenderableIcon returns the path of a PNG image at most size pixels square
+// for the icon at path. PNG icons that are small enough are returned as is;
+// larger PNGs and SVG, ICNS and XPM icons are decoded, rasterized or scaled
+// once and cached as PNG files keyed by path, modification time and size.
+func RenderableIcon(path string, size int) (string, error) {
+	var decode func([]byte, int) (image.Image, error)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".svg":
+		decode = rasterizeSVG
+	case ".icns":
+		decode = decodeICNS
+	case ".xpm":
+		decode = func(data []byte, _ int) (image.Image, error) { return decodeXPM(data) }
+	default:
+		if small, err := pngFits(path, size); err != nil || small {
+			return path, err
+		}
+		decode = func(data []byte, _ int) (image.Image, error) { return png.Decode(bytes.NewReader(data)) }
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s\x00%d\x00%d", path, info.ModTime().UnixNano(), size))
+	target := filepath.Join(dir, "icons", hex.EncodeToString(sum[:12])+".png")
+	if fileExists(target) {
+		return target, nil
+	}
+
+	if info.Size() > renderedIconLimit {
+		return "", fmt.Errorf("%s: icon is too large (%d bytes)", path, info.Size())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	img, err := decode(data, size)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	img = scaleIcon(img, size)
+
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".icon-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if err := png.Encode(tmp, img); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// pngFits reports whether the PNG image at path is no larger than size.
+// Files that are not PNG images are left for the UI to handle.
+func pngFits(path string, size int) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	config, err := png.DecodeConfig(file)
+	if err != nil {
+		return true, nil
+	}
+	return config.Width <= size && config.Height <= size, nil
+}
+
+// scaleIcon fits img into a size×size square, keeping its aspect ratio.
+func scaleIcon(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w == size && h == size || w == 0 || h == 0 {
+		return img
+	}
+	dw, dh := size, size
+	if w > h {
+		dh = max(1, h*size/w)
+	} else if h > w {
+		dw = max(1, w*size/h)
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	offset := image.Pt((size-dw)/2, (size-dh)/2)
+	draw.CatmullRom.Scale(dst, image.Rectangle{Min: offset, Max: offset.Add(image.Pt(dw, dh))}, img, bounds, draw.Over, nil)
+	return dst
+}
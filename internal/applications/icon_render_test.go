@@ -0,0 +1,97 @@
+package applications
+
+import (
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func copyIconFixture(t *testing.T, name string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, readIconFixture(t, name), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func decodedSize(t *testing.T, path string) (int, int) {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	config, err := png.DecodeConfig(file)
+	if err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	return config.Width, config.Height
+}
+
+func TestRenderableIcon(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	small := copyIconFixture(t, "small.png")
+	if got, err := RenderableIcon(small, 48); err != nil || got != small {
+		t.Errorf("RenderableIcon(small PNG) = %q, %v; want the file itself", got, err)
+	}
+
+	for _, name := range []string{"large.png", "multi.icns", "legacy.icns", "palette.xpm"} {
+		t.Run(name, func(t *testing.T) {
+			path := copyIconFixture(t, name)
+			got, err := RenderableIcon(path, 48)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got == path {
+				t.Fatal("RenderableIcon returned the source file")
+			}
+			if w, h := decodedSize(t, got); w != 48 || h != 48 {
+				t.Errorf("rendered size = %dx%d, want 48x48", w, h)
+			}
+		})
+	}
+}
+
+func TestRenderableIconCache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	path := copyIconFixture(t, "palette.xpm")
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := RenderableIcon(path, 48)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// With the modification time unchanged the cached PNG is used without
+	// reading the icon again, even though it no longer decodes.
+	if err := os.WriteFile(path, []byte("garbage"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if second, err := RenderableIcon(path, 48); err != nil || second != first {
+		t.Errorf("second RenderableIcon = %q, %v; want cached %q", second, err, first)
+	}
+
+	// Another size is rendered separately.
+	if other, err := RenderableIcon(path, 32); err == nil || other == first {
+		t.Errorf("RenderableIcon at another size = %q, %v; want a decode error", other, err)
+	}
+
+	// A changed icon is decoded again.
+	later := info.ModTime().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RenderableIcon(path, 48); err == nil {
+		t.Error("RenderableIcon used the cache after the icon changed")
+	}
+}
@@ -0,0 +1,552 @@
+package applications
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/SagenKoder/launcher/internal/config"
+)
+
+// ListIconSize is the pixel size icons are looked up and rendered at. List
+// rows draw icons at roughly 24dp, so 48px stays sharp on 2x displays as well.
+const ListIconSize = 48
+
+// iconExtensions is the lookup order mandated by the icon theme spec.
+var iconExtensions = []string{".png", ".svg", ".xpm"}
+
+func resolveIcon(iconValue, desktopPath string) string {
+	if iconValue == "" {
+		return ""
+	}
+
+	if filepath.IsAbs(iconValue) {
+		if fileExists(iconValue) {
+			return iconValue
+		}
+		return ""
+	}
+
+	if desktopPath != "" {
+		desktopDir := filepath.Dir(desktopPath)
+		if candidate := findIconWithExtensions(filepath.Join(desktopDir, iconValue)); candidate != "" {
+			return candidate
+		}
+	}
+
+	return defaultIconResolver().Lookup(iconValue, ListIconSize)
+}
+
+func findIconWithExtensions(base string) string {
+	if fileExists(base) {
+		return base
+	}
+	if filepath.Ext(base) != "" {
+		return ""
+	}
+	for _, ext := range iconExtensions {
+		candidate := base + ext
+		if fileExists(candidate) {
+			return candidate
+		}
+	}
+	return ""
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// iconResolver looks up icons following the freedesktop Icon Theme
+// Specification: the current theme first, then its Inherits= chain, then
+// hicolor and finally unthemed icons in the base directories.
+type iconResolver struct {
+	baseDirs []string
+	theme    string
+
+	mu      sync.Mutex
+	themes  map[string]*iconTheme
+	results map[iconQuery]string
+	// cached holds themes read from the icon cache; they are used once their
+	// directory stamps have been checked. The cache is read when the first
+	// theme is needed, on a goroutine resolving icons, rather than by
+	// whoever first asks for the resolver. dirty is set when a theme had to
+	// be indexed from disk and the cache should be rewritten.
+	cached       map[string]*iconTheme
+	cachedLoaded bool
+	dirty        bool
+}
+
+type iconQuery struct {
+	name string
+	size int
+}
+
+// iconTheme is a parsed index.theme together with the icons found in each of
+// its directories across all base directories.
+// Fields are exported so themes can be stored in the icon cache.
+type iconTheme struct {
+	Name     string
+	Inherits []string
+	Dirs     []iconThemeDir
+	// Stamps records the modification time of every directory that was
+	// indexed, keyed by path, so cached themes can be validated.
+	Stamps map[string]int64
+}
+
+type iconThemeDir struct {
+	Size      int
+	Scale     int
+	MinSize   int
+	MaxSize   int
+	Threshold int
+	Kind      string
+	// Icons maps an icon name to its file; earlier base directories and
+	// extensions win.
+	Icons map[string]string
+}
+
+var (
+	iconResolverOnce     sync.Once
+	iconResolverInstance *iconResolver
+)
+
+func defaultIconResolver() *iconResolver {
+	iconResolverOnce.Do(func() {
+		iconResolverInstance = newIconResolver(iconBaseDirs(), iconThemeName())
+	})
+	return iconResolverInstance
+}
+
+// iconThemeName returns the session's icon theme, looked up once.
+var iconThemeName = sync.OnceValue(currentIconTheme)
+
+func newIconResolver(baseDirs []string, theme string) *iconResolver {
+	if theme == "" {
+		theme = "hicolor"
+	}
+	return &iconResolver{
+		baseDirs: baseDirs,
+		theme:    theme,
+		themes:   make(map[string]*iconTheme),
+		results:  make(map[iconQuery]string),
+	}
+}
+
+// invalidate forgets resolved lookups after the icon directories changed.
+// Indexed themes are kept as cache candidates, so only themes whose directory
+// stamps no longer match are indexed again.
+func (r *iconResolver) invalidate() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.loadCached()
+	if r.cached == nil {
+		r.cached = make(map[string]*iconTheme)
+	}
+	for name, theme := range r.themes {
+		if theme != nil {
+			r.cached[name] = theme
+		}
+	}
+	r.themes = make(map[string]*iconTheme)
+	r.results = make(map[iconQuery]string)
+}
+
+// watchedDirs returns the base directories and every theme directory indexed
+// so far, i.e. the directories whose changes can alter a lookup result.
+func (r *iconResolver) watchedDirs() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	dirs := append([]string(nil), r.baseDirs...)
+	for _, theme := range r.themes {
+		if theme == nil {
+			continue
+		}
+		for path := range theme.Stamps {
+			if filepath.Base(path) == "index.theme" {
+				path = filepath.Dir(path)
+			}
+			dirs = append(dirs, path)
+		}
+	}
+	return dirs
+}
+
+// Lookup returns the file for the named icon closest to size pixels, or an
+// empty string when no theme or base directory provides it.
+func (r *iconResolver) Lookup(name string, size int) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return ""
+	}
+	// Icon keys should not carry an extension, but plenty of desktop files
+	// use "foo.png" anyway.
+	for _, ext := range iconExtensions {
+		if strings.EqualFold(filepath.Ext(name), ext) {
+			name = strings.TrimSuffix(name, filepath.Ext(name))
+			break
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	query := iconQuery{name: name, size: size}
+	if path, ok := r.results[query]; ok {
+		return path
+	}
+
+	path := ""
+	visited := make(map[string]bool)
+	for _, theme := range append([]string{r.theme}, "hicolor") {
+		if path = r.lookupInTheme(theme, name, size, visited); path != "" {
+			break
+		}
+	}
+	if path == "" {
+		path = r.lookupUnthemed(name)
+	}
+	r.results[query] = path
+	return path
+}
+
+func (r *iconResolver) lookupInTheme(themeName, icon string, size int, visited map[string]bool) string {
+	if visited[themeName] {
+		return ""
+	}
+	visited[themeName] = true
+
+	theme := r.loadTheme(themeName)
+	if theme == nil {
+		return ""
+	}
+	if path := theme.lookup(icon, size, 1); path != "" {
+		return path
+	}
+	for _, parent := range theme.Inherits {
+		if path := r.lookupInTheme(parent, icon, size, visited); path != "" {
+			return path
+		}
+	}
+	return ""
+}
+
+func (r *iconResolver) lookupUnthemed(name string) string {
+	for _, dir := range r.baseDirs {
+		if path := findIconWithExtensions(filepath.Join(dir, name)); path != "" {
+			return path
+		}
+	}
+	return ""
+}
+
+// loadCached reads the icon cache the first time it is needed.
+func (r *iconResolver) loadCached() {
+	if !r.cachedLoaded {
+		r.cached = loadIconCache(r.baseDirs)
+		r.cachedLoaded = true
+	}
+}
+
+// loadTheme parses index.theme from the first base directory that has one and
+// indexes the theme's directories. Missing themes are remembered as nil.
+func (r *iconResolver) loadTheme(name string) *iconTheme {
+	if theme, ok := r.themes[name]; ok {
+		return theme
+	}
+	r.loadCached()
+	if cached, ok := r.cached[name]; ok && stampsValid(cached.Stamps) {
+		r.themes[name] = cached
+		return cached
+	}
+	r.themes[name] = nil
+
+	stamps := make(map[string]int64)
+	var groups map[string]desktopGroup
+	for _, base := range r.baseDirs {
+		indexPath := filepath.Join(base, name, "index.theme")
+		stamps[indexPath] = pathStamp(indexPath)
+		parsed, err := readDesktopGroups(indexPath)
+		if err == nil {
+			groups = parsed
+			break
+		}
+	}
+	header, ok := groups["Icon Theme"]
+	if !ok {
+		return nil
+	}
+
+	theme := &iconTheme{
+		Name:     name,
+		Inherits: splitCommaList(header["Inherits"]),
+		Stamps:   stamps,
+	}
+	subdirs := append(splitCommaList(header["Directories"]), splitCommaList(header["ScaledDirectories"])...)
+	seen := make(map[string]bool, len(subdirs))
+	for _, subdir := range subdirs {
+		group, ok := groups[subdir]
+		if !ok || seen[subdir] {
+			continue
+		}
+		seen[subdir] = true
+		dir := parseIconThemeDir(group)
+		if dir.Size <= 0 {
+			continue
+		}
+		for _, base := range r.baseDirs {
+			path := filepath.Join(base, name, subdir)
+			stamps[path] = pathStamp(path)
+			indexIconDir(dir.Icons, path)
+		}
+		if len(dir.Icons) > 0 {
+			theme.Dirs = append(theme.Dirs, dir)
+		}
+	}
+	r.themes[name] = theme
+	r.dirty = true
+	return theme
+}
+
+func parseIconThemeDir(group desktopGroup) iconThemeDir {
+	dir := iconThemeDir{
+		Size:      atoiDefault(group["Size"], 0),
+		Scale:     atoiDefault(group["Scale"], 1),
+		Threshold: atoiDefault(group["Threshold"], 2),
+		Kind:      group["Type"],
+		Icons:     make(map[string]string),
+	}
+	if dir.Kind == "" {
+		dir.Kind = "Threshold"
+	}
+	dir.MinSize = atoiDefault(group["MinSize"], dir.Size)
+	dir.MaxSize = atoiDefault(group["MaxSize"], dir.Size)
+	return dir
+}
+
+func indexIconDir(icons map[string]string, dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, ext := range iconExtensions {
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ext) {
+				continue
+			}
+			name := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+			if _, ok := icons[name]; !ok {
+				icons[name] = filepath.Join(dir, entry.Name())
+			}
+		}
+	}
+}
+
+// lookup implements LookupIcon from the spec: an exact size match wins,
+// otherwise the directory with the smallest size distance is used.
+func (t *iconTheme) lookup(name string, size, scale int) string {
+	best := ""
+	bestDistance := -1
+	for _, dir := range t.Dirs {
+		path, ok := dir.Icons[name]
+		if !ok {
+			continue
+		}
+		if dir.matchesSize(size, scale) {
+			return path
+		}
+		if distance := dir.sizeDistance(size, scale); bestDistance < 0 || distance < bestDistance {
+			best, bestDistance = path, distance
+		}
+	}
+	return best
+}
+
+func (d iconThemeDir) matchesSize(size, scale int) bool {
+	if d.Scale != scale {
+		return false
+	}
+	switch d.Kind {
+	case "Fixed":
+		return d.Size == size
+	case "Scalable":
+		return d.MinSize <= size && size <= d.MaxSize
+	default:
+		return d.Size-d.Threshold <= size && size <= d.Size+d.Threshold
+	}
+}
+
+func (d iconThemeDir) sizeDistance(size, scale int) int {
+	want := size * scale
+	switch d.Kind {
+	case "Fixed":
+		return absInt(d.Size*d.Scale - want)
+	case "Scalable":
+		if want < d.MinSize*d.Scale {
+			return d.MinSize*d.Scale - want
+		}
+		if want > d.MaxSize*d.Scale {
+			return want - d.MaxSize*d.Scale
+		}
+		return 0
+	default:
+		if want < (d.Size-d.Threshold)*d.Scale {
+			return (d.Size-d.Threshold)*d.Scale - want
+		}
+		if want > (d.Size+d.Threshold)*d.Scale {
+			return want - (d.Size+d.Threshold)*d.Scale
+		}
+		return 0
+	}
+}
+
+// currentIconTheme returns the icon theme configured for the launcher, falling
+// back to the desktop's GTK or KDE setting.
+func currentIconTheme() string {
+	if cfg, err := config.Load(); err == nil {
+		if theme := strings.TrimSpace(cfg.Icons.Theme); theme != "" {
+			return theme
+		}
+	}
+	if theme := strings.TrimSpace(os.Getenv("LAUNCHER_ICON_THEME")); theme != "" {
+		return theme
+	}
+
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			configHome = filepath.Join(home, ".config")
+		}
+	}
+	if configHome != "" {
+		if strings.Contains(strings.ToUpper(os.Getenv("XDG_CURRENT_DESKTOP")), "KDE") {
+			if theme := iniValue(filepath.Join(configHome, "kdeglobals"), "Icons", "Theme"); theme != "" {
+				return theme
+			}
+		}
+		for _, gtk := range []string{"gtk-4.0", "gtk-3.0"} {
+			if theme := iniValue(filepath.Join(configHome, gtk, "settings.ini"), "Settings", "gtk-icon-theme-name"); theme != "" {
+				return theme
+			}
+		}
+	}
+	if _, err := exec.LookPath("gsettings"); err == nil {
+		out, err := exec.Command("gsettings", "get", "org.gnome.desktop.interface", "icon-theme").Output()
+		if err == nil {
+			if theme := strings.Trim(strings.TrimSpace(string(out)), "'"); theme != "" {
+				return theme
+			}
+		}
+	}
+	return "hicolor"
+}
+
+func iniValue(path, section, key string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	inSection := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			inSection = line[1:len(line)-1] == section
+			continue
+		}
+		if !inSection {
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if ok && strings.TrimSpace(k) == key {
+			return strings.Trim(strings.TrimSpace(v), `"`)
+		}
+	}
+	return ""
+}
+
+func splitCommaList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func atoiDefault(value string, fallback int) int {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return fallback
+	}
+	return n
+}
+
+func absInt(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// iconBaseDirs lists the icon base directories in lookup order: the user's
+// data home, ~/.icons, each XDG data dir and finally the pixmaps directories
+// used for unthemed icons.
+func iconBaseDirs() []string {
+	dirs := make([]string, 0, 8)
+
+	if dataHome := os.Getenv("XDG_DATA_HOME"); dataHome != "" {
+		dirs = append(dirs, filepath.Join(dataHome, "icons"))
+	} else if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".local/share/icons"))
+	}
+
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".icons"))
+	}
+
+	dataDirs := xdgDataDirs()
+	for _, dir := range dataDirs {
+		dirs = append(dirs, filepath.Join(dir, "icons"))
+	}
+
+	if dataHome := os.Getenv("XDG_DATA_HOME"); dataHome != "" {
+		dirs = append(dirs, filepath.Join(dataHome, "pixmaps"))
+	} else if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".local/share/pixmaps"))
+	}
+	for _, dir := range dataDirs {
+		dirs = append(dirs, filepath.Join(dir, "pixmaps"))
+	}
+	dirs = append(dirs, "/usr/share/pixmaps")
+	return dirs
+}
+
+func xdgDataDirs() []string {
+	dataDirsEnv := os.Getenv("XDG_DATA_DIRS")
+	if dataDirsEnv == "" {
+		dataDirsEnv = "/usr/local/share:/usr/share"
+	}
+	var dirs []string
+	for _, dir := range strings.Split(dataDirsEnv, ":") {
+		if dir = strings.TrimSpace(dir); dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// ResolveIcon returns the file for an icon given as a theme icon name or an
+// absolute path, or "" when there is none. The first lookup indexes the icon
+// themes, so it belongs off the UI goroutine.
+func ResolveIcon(icon string) string {
+	return resolveIcon(icon, "")
+}
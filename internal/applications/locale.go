@@ -0,0 +1,48 @@
+package applications
+
+import (
+	"os"
+	"strings"
+)
+
+// messageLocales returns the locale suffixes to try when resolving localized
+// keys, most specific first, based on the LC_MESSAGES value in effect.
+func messageLocales() []string {
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := strings.TrimSpace(os.Getenv(env)); value != "" {
+			return localeVariants(value)
+		}
+	}
+	return nil
+}
+
+// localeVariants expands a POSIX locale of the form
+// lang_COUNTRY.ENCODING@MODIFIER into the matching order defined by the
+// Desktop Entry Specification: lang_COUNTRY@MODIFIER, lang_COUNTRY,
+// lang@MODIFIER and lang. The encoding is ignored.
+func localeVariants(locale string) []string {
+	var modifier string
+	if idx := strings.IndexByte(locale, '@'); idx >= 0 {
+		modifier = locale[idx+1:]
+		locale = locale[:idx]
+	}
+	if idx := strings.IndexByte(locale, '.'); idx >= 0 {
+		locale = locale[:idx]
+	}
+	lang, country, _ := strings.Cut(locale, "_")
+	if lang == "" || lang == "C" || lang == "POSIX" {
+		return nil
+	}
+
+	variants := make([]string, 0, 4)
+	if country != "" && modifier != "" {
+		variants = append(variants, lang+"_"+country+"@"+modifier)
+	}
+	if country != "" {
+		variants = append(variants, lang+"_"+country)
+	}
+	if modifier != "" {
+		variants = append(variants, lang+"@"+modifier)
+	}
+	return append(variants, lang)
+}
@@ -0,0 +1,312 @@
+package applications
+
+import (
+	"bufio"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// OpenTarget reports whether text, as typed or pasted into the launcher,
+// names an existing file or a URL, and returns it in the form passed to
+// ExpandExec: file:// URLs and ~ are turned into absolute paths.
+func OpenTarget(text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	// File managers quote paths containing spaces when copying them.
+	if len(text) >= 2 && (text[0] == '\'' || text[0] == '"') && text[len(text)-1] == text[0] {
+		text = text[1 : len(text)-1]
+	}
+	if text == "" {
+		return "", false
+	}
+
+	if strings.HasPrefix(text, "file://") {
+		path, ok := localPath(text)
+		if !ok || !fileOrDirExists(path) {
+			return "", false
+		}
+		return path, true
+	}
+	if scheme, _, ok := strings.Cut(text, ":"); ok && validScheme(scheme) {
+		if _, err := url.Parse(text); err == nil && (strings.Contains(text, "://") || strings.EqualFold(scheme, "mailto")) {
+			return text, true
+		}
+	}
+
+	path := text
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", false
+		}
+		path = filepath.Join(home, rest)
+	}
+	if !filepath.IsAbs(path) || !fileOrDirExists(path) {
+		return "", false
+	}
+	return filepath.Clean(path), true
+}
+
+func validScheme(scheme string) bool {
+	if scheme == "" || !isASCIILetter(scheme[0]) {
+		return false
+	}
+	for i := 1; i < len(scheme); i++ {
+		c := scheme[i]
+		if !isASCIILetter(c) && !(c >= '0' && c <= '9') && c != '+' && c != '-' && c != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func fileOrDirExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// MimeTypeOf returns the MIME type of a target returned by OpenTarget. URLs
+// map to x-scheme-handler/<scheme>; files are matched by extension through
+// the shared MIME database, then by content.
+func MimeTypeOf(target string) string {
+	if !filepath.IsAbs(target) {
+		scheme, _, _ := strings.Cut(target, ":")
+		return "x-scheme-handler/" + strings.ToLower(scheme)
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return "application/octet-stream"
+	}
+	if info.IsDir() {
+		return "inode/directory"
+	}
+	if ext := filepath.Ext(target); ext != "" {
+		if mimeType := mime.TypeByExtension(strings.ToLower(ext)); mimeType != "" {
+			return canonicalMimeType(mimeType)
+		}
+	}
+	file, err := os.Open(target)
+	if err != nil {
+		return "application/octet-stream"
+	}
+	defer file.Close()
+	head := make([]byte, 512)
+	n, _ := file.Read(head)
+	if n == 0 {
+		return "application/x-zerosize"
+	}
+	return canonicalMimeType(http.DetectContentType(head[:n]))
+}
+
+// canonicalMimeType drops parameters such as "; charset=utf-8".
+func canonicalMimeType(mimeType string) string {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(mimeType))
+	}
+	return mediaType
+}
+
+// Handlers returns the applications able to open mimeType, following the
+// MIME applications associations specification: the default application from
+// the mimeapps.list hierarchy comes first, then added associations, then
+// applications declaring the type (or a broader type it is a subclass of) in
+// MimeType=. Removed associations are left out.
+func Handlers(apps []Application, mimeType string) []Application {
+	byID := make(map[string]Application, len(apps))
+	for _, app := range apps {
+		if app.ID != "" && app.ActionID == "" {
+			byID[app.ID] = app
+		}
+	}
+
+	types := mimeTypeChain(mimeType)
+	assoc := loadMimeAssociations(currentDesktopEnv().desktops)
+
+	var handlers []Application
+	seen := make(map[string]struct{})
+	add := func(id string) {
+		if _, ok := seen[id]; ok {
+			return
+		}
+		if app, ok := byID[id]; ok {
+			seen[id] = struct{}{}
+			handlers = append(handlers, app)
+		}
+	}
+
+	// The first installed default for the most specific type wins.
+defaults:
+	for _, t := range types {
+		for _, id := range assoc.defaults[t] {
+			if _, ok := byID[id]; ok {
+				add(id)
+				break defaults
+			}
+		}
+	}
+	for _, t := range types {
+		for _, id := range assoc.added[t] {
+			add(id)
+		}
+	}
+
+	var declared []Application
+	for _, app := range apps {
+		if app.ID == "" || app.ActionID != "" {
+			continue
+		}
+		if _, ok := seen[app.ID]; ok {
+			continue
+		}
+		for _, t := range types {
+			if slices.Contains(app.MimeTypes, t) && !slices.Contains(assoc.removed[t], app.ID) {
+				declared = append(declared, app)
+				break
+			}
+		}
+	}
+	sortApplications(declared)
+	return append(handlers, declared...)
+}
+
+// mimeTypeChain returns mimeType followed by the types it is an alias or
+// subclass of, most specific first.
+func mimeTypeChain(mimeType string) []string {
+	db := loadMimeDatabase()
+	if canonical, ok := db.aliases[mimeType]; ok {
+		mimeType = canonical
+	}
+	chain := []string{mimeType}
+	for i := 0; i < len(chain); i++ {
+		parents := db.parents[chain[i]]
+		// Every text type is implicitly a subclass of text/plain.
+		if strings.HasPrefix(chain[i], "text/") && chain[i] != "text/plain" {
+			parents = append(parents, "text/plain")
+		}
+		for _, parent := range parents {
+			if !slices.Contains(chain, parent) {
+				chain = append(chain, parent)
+			}
+		}
+	}
+	return chain
+}
+
+type mimeDatabase struct {
+	aliases map[string]string
+	parents map[string][]string
+}
+
+// loadMimeDatabase reads the aliases and subclasses files generated by
+// update-mime-database. Directories earlier in XDG_DATA_DIRS take precedence.
+func loadMimeDatabase() mimeDatabase {
+	db := mimeDatabase{aliases: make(map[string]string), parents: make(map[string][]string)}
+	dirs := append([]string{dataHomeDir()}, xdgDataDirs()...)
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if dirs[i] == "" {
+			continue
+		}
+		readMimePairs(filepath.Join(dirs[i], "mime", "aliases"), func(alias, canonical string) {
+			db.aliases[alias] = canonical
+		})
+		readMimePairs(filepath.Join(dirs[i], "mime", "subclasses"), func(child, parent string) {
+			if !slices.Contains(db.parents[child], parent) {
+				db.parents[child] = append(db.parents[child], parent)
+			}
+		})
+	}
+	return db
+}
+
+func readMimePairs(path string, fn func(a, b string)) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && !strings.HasPrefix(fields[0], "#") {
+			fn(fields[0], fields[1])
+		}
+	}
+}
+
+type mimeAssociations struct {
+	// defaults lists candidate default applications per type in precedence
+	// order; the first installed one is used.
+	defaults map[string][]string
+	added    map[string][]string
+	removed  map[string][]string
+}
+
+// loadMimeAssociations merges the mimeapps.list files in precedence order.
+// A removal only hides associations from files of lower precedence, so it is
+// applied while merging rather than at the end.
+func loadMimeAssociations(desktops []string) mimeAssociations {
+	assoc := mimeAssociations{
+		defaults: make(map[string][]string),
+		added:    make(map[string][]string),
+		removed:  make(map[string][]string),
+	}
+	for _, path := range mimeappsListPaths(desktops) {
+		groups, err := readDesktopGroups(path)
+		if err != nil {
+			continue
+		}
+		for mimeType := range groups["Default Applications"] {
+			assoc.defaults[mimeType] = append(assoc.defaults[mimeType], groups["Default Applications"].list(mimeType)...)
+		}
+		for mimeType := range groups["Added Associations"] {
+			for _, id := range groups["Added Associations"].list(mimeType) {
+				if !slices.Contains(assoc.removed[mimeType], id) {
+					assoc.added[mimeType] = append(assoc.added[mimeType], id)
+				}
+			}
+		}
+		for mimeType := range groups["Removed Associations"] {
+			assoc.removed[mimeType] = append(assoc.removed[mimeType], groups["Removed Associations"].list(mimeType)...)
+		}
+	}
+	return assoc
+}
+
+// mimeappsListPaths returns the mimeapps.list files in precedence order:
+// config home, config dirs, data home and data dirs, each with the
+// desktop-specific files before the generic one.
+func mimeappsListPaths(desktops []string) []string {
+	var dirs []string
+	if configHome := os.Getenv("XDG_CONFIG_HOME"); configHome != "" {
+		dirs = append(dirs, configHome)
+	} else if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".config"))
+	}
+	configDirs := os.Getenv("XDG_CONFIG_DIRS")
+	if configDirs == "" {
+		configDirs = "/etc/xdg"
+	}
+	dirs = append(dirs, filepath.SplitList(configDirs)...)
+	dirs = append(dirs, desktopDirs()...)
+
+	var paths []string
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		for _, desktop := range desktops {
+			paths = append(paths, filepath.Join(dir, strings.ToLower(desktop)+"-mimeapps.list"))
+		}
+		paths = append(paths, filepath.Join(dir, "mimeapps.list"))
+	}
+	return paths
+}
@@ -0,0 +1,133 @@
+package applications
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeMimeFixtures isolates discovery and writes files, keyed by their path
+// relative to the directory holding the XDG directories.
+func writeMimeFixtures(t *testing.T, files map[string]string) {
+	t.Helper()
+	apps := isolateDiscovery(t)
+	root := filepath.Dir(filepath.Dir(apps))
+	t.Setenv("XDG_CONFIG_DIRS", filepath.Join(root, "config-dirs"))
+	t.Setenv("XDG_CURRENT_DESKTOP", "GNOME")
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	writeMimeFixtures(t, map[string]string{
+		"config/gnome-mimeapps.list": "[Default Applications]\ntext/html=gnome-browser.desktop;\n",
+		"config/mimeapps.list": `[Default Applications]
+text/html=generic-browser.desktop;
+x-scheme-handler/https=missing.desktop;firefox.desktop;
+text/plain=editor.desktop;
+
+[Added Associations]
+image/png=paint.desktop;
+
+[Removed Associations]
+image/png=gimp.desktop;
+`,
+		"config-dirs/mimeapps.list": "[Default Applications]\nx-scheme-handler/https=chromium.desktop;\n",
+		"data/applications/mimeapps.list": `[Added Associations]
+image/png=gimp.desktop;viewer.desktop;
+
+[Removed Associations]
+image/png=paint.desktop;
+`,
+	})
+	apps := []Application{
+		{ID: "gnome-browser.desktop", Name: "GNOME Browser", MimeTypes: []string{"text/html"}},
+		{ID: "generic-browser.desktop", Name: "Generic Browser", MimeTypes: []string{"text/html"}},
+		{ID: "firefox.desktop", Name: "Firefox", MimeTypes: []string{"x-scheme-handler/https"}},
+		{ID: "chromium.desktop", Name: "Chromium", MimeTypes: []string{"x-scheme-handler/https"}},
+		{ID: "gimp.desktop", Name: "GIMP", MimeTypes: []string{"image/png"}},
+		{ID: "paint.desktop", Name: "Paint"},
+		{ID: "viewer.desktop", Name: "Viewer"},
+		{ID: "shotwell.desktop", Name: "Shotwell", MimeTypes: []string{"image/png"}},
+		{ID: "editor.desktop", Name: "Editor", MimeTypes: []string{"text/plain"}},
+		{ID: "notes.desktop", Name: "Notes", MimeTypes: []string{"text/markdown", "text/plain"}},
+		{ID: "notes.desktop", ActionID: "new", Name: "New Note", MimeTypes: []string{"text/markdown"}},
+	}
+	tests := []struct {
+		name     string
+		mimeType string
+		want     []string
+	}{
+		{"desktop-specific default first", "text/html", []string{"gnome-browser.desktop", "editor.desktop", "generic-browser.desktop", "notes.desktop"}},
+		{"first installed default", "x-scheme-handler/https", []string{"firefox.desktop", "chromium.desktop"}},
+		{"removals only hide lower-precedence adds", "image/png", []string{"paint.desktop", "viewer.desktop", "shotwell.desktop"}},
+		{"text falls back to text/plain", "text/x-csrc", []string{"editor.desktop", "notes.desktop"}},
+		{"specific type before text/plain", "text/markdown", []string{"editor.desktop", "notes.desktop"}},
+		{"no handlers", "application/x-unknown", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, app := range Handlers(apps, tt.mimeType) {
+				if app.ActionID != "" {
+					t.Errorf("action %s offered as a handler", app.ActionID)
+				}
+				got = append(got, app.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Handlers(%q) = %q, want %q", tt.mimeType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadMimeAssociations(t *testing.T) {
+	writeMimeFixtures(t, map[string]string{
+		"config/gnome-mimeapps.list":        "[Default Applications]\ntext/html=gnome-browser.desktop;\n",
+		"config/mimeapps.list":              "[Default Applications]\ntext/html=generic-browser.desktop;\n\n[Removed Associations]\nimage/png=gimp.desktop;\n",
+		"config-dirs/kde-mimeapps.list":     "[Default Applications]\ntext/html=kde-browser.desktop;\n",
+		"data/applications/mimeapps.list":   "[Added Associations]\nimage/png=gimp.desktop;viewer.desktop;\n",
+		"system/applications/mimeapps.list": "[Default Applications]\ntext/html=system-browser.desktop;\n",
+	})
+	assoc := loadMimeAssociations([]string{"GNOME"})
+	want := mimeAssociations{
+		defaults: map[string][]string{"text/html": {"gnome-browser.desktop", "generic-browser.desktop", "system-browser.desktop"}},
+		added:    map[string][]string{"image/png": {"viewer.desktop"}},
+		removed:  map[string][]string{"image/png": {"gimp.desktop"}},
+	}
+	if !reflect.DeepEqual(assoc, want) {
+		t.Errorf("loadMimeAssociations = %+v, want %+v", assoc, want)
+	}
+}
+
+func TestMimeTypeChain(t *testing.T) {
+	writeMimeFixtures(t, map[string]string{
+		"data/mime/aliases":      "text/x-c text/x-csrc\n",
+		"system/mime/aliases":    "# comment\ntext/x-c text/x-chdr\n",
+		"system/mime/subclasses": "application/x-shellscript application/x-executable\napplication/x-shellscript text/plain\ntext/x-csrc text/plain\n",
+	})
+	tests := []struct {
+		mimeType string
+		want     []string
+	}{
+		{"text/plain", []string{"text/plain"}},
+		{"image/png", []string{"image/png"}},
+		{"text/markdown", []string{"text/markdown", "text/plain"}},
+		// The data home's alias overrides the system one.
+		{"text/x-c", []string{"text/x-csrc", "text/plain"}},
+		{"application/x-shellscript", []string{"application/x-shellscript", "application/x-executable", "text/plain"}},
+	}
+	for _, tt := range tests {
+		if got := mimeTypeChain(tt.mimeType); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("mimeTypeChain(%q) = %q, want %q", tt.mimeType, got, tt.want)
+		}
+	}
+}
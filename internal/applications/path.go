@@ -0,0 +1,143 @@
+package applications
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// pathSource lists the executables on $PATH. Directory listings are kept
+// between calls and only re-read when a directory's modification time changes,
+// so re-listing after a change in one directory is cheap.
+type pathSource struct {
+	mu      sync.Mutex
+	listing map[string]pathListing
+}
+
+type pathListing struct {
+	modTime time.Time
+	names   []string
+}
+
+func (*pathSource) Name() string { return SourcePath }
+
+// Dirs returns the absolute $PATH entries in order, without duplicates.
+// Relative entries such as "." are ignored.
+func (*pathSource) Dirs() []string {
+	var dirs []string
+	seen := make(map[string]struct{})
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if !filepath.IsAbs(dir) {
+			continue
+		}
+		dir = filepath.Clean(dir)
+		if _, ok := seen[dir]; ok {
+			continue
+		}
+		seen[dir] = struct{}{}
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// List returns one application per command name. Like the shell, the first
+// directory on $PATH providing a name wins.
+func (s *pathSource) List() ([]Application, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dirs := s.Dirs()
+	listing := make(map[string]pathListing, len(dirs))
+	seen := make(map[string]struct{})
+	var (
+		apps []Application
+		errs []error
+	)
+	for _, dir := range dirs {
+		entry, err := s.list(dir)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, fmt.Errorf("read dir %s: %w", dir, err))
+			}
+			continue
+		}
+		listing[dir] = entry
+		for _, name := range entry.names {
+			if _, ok := seen[name]; ok {
+				continue
+			}
+			seen[name] = struct{}{}
+			apps = append(apps, commandApplication(name, filepath.Join(dir, name)))
+		}
+	}
+	s.listing = listing
+	return apps, errors.Join(errs...)
+}
+
+func (s *pathSource) list(dir string) (pathListing, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return pathListing{}, err
+	}
+	if cached, ok := s.listing[dir]; ok && cached.modTime.Equal(info.ModTime()) {
+		return cached, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return pathListing{}, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		// Stat rather than entry.Info so symlinks are followed.
+		info, err := os.Stat(filepath.Join(dir, entry.Name()))
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0o111 == 0 {
+			continue
+		}
+		names = append(names, entry.Name())
+	}
+	return pathListing{modTime: info.ModTime(), names: names}, nil
+}
+
+func commandApplication(name, path string) Application {
+	return Application{
+		ID:   name,
+		Name: name,
+		// Run the command by name so it resolves exactly as it would in a
+		// shell; the first match on $PATH is path.
+		Exec:   quoteExecArg(execToken{text: escapeFieldCodes(name)}),
+		Path:   path,
+		Source: SourcePath,
+	}
+}
+
+// CommandLine returns an application that runs line, an arbitrary shell
+// command line, through the user's shell.
+func CommandLine(line string) Application {
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+	return Application{
+		Name:    line,
+		Comment: "Run command",
+		Exec: joinExec([]execToken{
+			{text: escapeFieldCodes(shell)},
+			{text: "-c"},
+			{text: escapeFieldCodes(line), quoted: true},
+		}),
+		Source: SourcePath,
+	}
+}
+
+// escapeFieldCodes escapes percent signs so ExpandExec passes text through
+// unchanged.
+func escapeFieldCodes(text string) string {
+	return strings.ReplaceAll(text, "%", "%%")
+}
@@ -0,0 +1,601 @@
+package applications
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// parsePlist decodes an XML or binary property list. Dictionaries decode to
+// map[string]any, arrays to []any, integers to int64 (or uint64 when too
+// large), reals to float64, dates to time.Time and data to []byte.
+func parsePlist(data []byte) (any, error) {
+	if bytes.HasPrefix(data, []byte("bplist00")) {
+		return parseBinaryPlist(data)
+	}
+	return parseXMLPlist(data)
+}
+
+func parseXMLPlist(data []byte) (any, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	// Info.plist files declare UTF-8; tolerate other labels rather than fail.
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) { return input, nil }
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("plist: %w", err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local == "plist" {
+			continue
+		}
+		return decodeXMLPlistValue(decoder, start, 0)
+	}
+}
+
+// maxPlistDepth bounds nesting so malformed files cannot exhaust the stack.
+const maxPlistDepth = 64
+
+func decodeXMLPlistValue(decoder *xml.Decoder, start xml.StartElement, depth int) (any, error) {
+	if depth > maxPlistDepth {
+		return nil, errors.New("plist: nested too deeply")
+	}
+	switch start.Name.Local {
+	case "dict":
+		dict := make(map[string]any)
+		var key *string
+		for {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, fmt.Errorf("plist: %w", err)
+			}
+			switch t := token.(type) {
+			case xml.EndElement:
+				return dict, nil
+			case xml.StartElement:
+				if t.Name.Local == "key" {
+					text, err := xmlText(decoder)
+					if err != nil {
+						return nil, err
+					}
+					key = &text
+					continue
+				}
+				value, err := decodeXMLPlistValue(decoder, t, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				if key == nil {
+					return nil, errors.New("plist: dict value without key")
+				}
+				dict[*key] = value
+				key = nil
+			}
+		}
+	case "array":
+		var array []any
+		for {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, fmt.Errorf("plist: %w", err)
+			}
+			switch t := token.(type) {
+			case xml.EndElement:
+				return array, nil
+			case xml.StartElement:
+				value, err := decodeXMLPlistValue(decoder, t, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				array = append(array, value)
+			}
+		}
+	case "true", "false":
+		if err := decoder.Skip(); err != nil {
+			return nil, fmt.Errorf("plist: %w", err)
+		}
+		return start.Name.Local == "true", nil
+	}
+
+	text, err := xmlText(decoder)
+	if err != nil {
+		return nil, err
+	}
+	switch start.Name.Local {
+	case "string":
+		return text, nil
+	case "integer":
+		text = strings.TrimSpace(text)
+		if n, err := strconv.ParseInt(text, 0, 64); err == nil {
+			return n, nil
+		}
+		n, err := strconv.ParseUint(text, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("plist: invalid integer %q", text)
+		}
+		return n, nil
+	case "real":
+		f, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		if err != nil {
+			return nil, fmt.Errorf("plist: invalid real %q", text)
+		}
+		return f, nil
+	case "date":
+		t, err := time.Parse(time.RFC3339, strings.TrimSpace(text))
+		if err != nil {
+			return nil, fmt.Errorf("plist: invalid date %q", text)
+		}
+		return t, nil
+	case "data":
+		clean := strings.Map(func(r rune) rune {
+			if r == ' ' || r == '\t' || r == '\n' || r == '\r' {
+				return -1
+			}
+			return r
+		}, text)
+		b, err := base64.StdEncoding.DecodeString(clean)
+		if err != nil {
+			return nil, fmt.Errorf("plist: invalid data: %w", err)
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("plist: unknown element <%s>", start.Name.Local)
+}
+
+// xmlText returns the character data up to the end of the current element.
+func xmlText(decoder *xml.Decoder) (string, error) {
+	var b strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", fmt.Errorf("plist: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.CharData:
+			b.Write(t)
+		case xml.EndElement:
+			return b.String(), nil
+		case xml.StartElement:
+			return "", fmt.Errorf("plist: unexpected <%s>", t.Name.Local)
+		}
+	}
+}
+
+// binaryPlist decodes the bplist00 format: a header, the objects, an offset
+// table locating each object and a 32-byte trailer.
+type binaryPlist struct {
+	data       []byte
+	offsets    []uint64
+	refSize    int
+	visiting   map[uint64]bool
+	objectsEnd uint64
+}
+
+func parseBinaryPlist(data []byte) (any, error) {
+	if len(data) < 8+32 {
+		return nil, errors.New("plist: truncated binary plist")
+	}
+	trailer := data[len(data)-32:]
+	offsetSize := int(trailer[6])
+	refSize := int(trailer[7])
+	numObjects := binary.BigEndian.Uint64(trailer[8:])
+	topObject := binary.BigEndian.Uint64(trailer[16:])
+	tableOffset := binary.BigEndian.Uint64(trailer[24:])
+
+	if offsetSize < 1 || offsetSize > 8 || refSize < 1 || refSize > 8 {
+		return nil, errors.New("plist: invalid trailer")
+	}
+	tableEnd := uint64(len(data) - 32)
+	if numObjects == 0 || topObject >= numObjects || tableOffset > tableEnd ||
+		numObjects > (tableEnd-tableOffset)/uint64(offsetSize) {
+		return nil, errors.New("plist: invalid trailer")
+	}
+
+	p := &binaryPlist{
+		data:       data,
+		offsets:    make([]uint64, numObjects),
+		refSize:    refSize,
+		visiting:   make(map[uint64]bool),
+		objectsEnd: tableOffset,
+	}
+	for i := range p.offsets {
+		start := tableOffset + uint64(i*offsetSize)
+		p.offsets[i] = readUintN(data[start : start+uint64(offsetSize)])
+	}
+	return p.object(topObject, 0)
+}
+
+func readUintN(b []byte) uint64 {
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	return n
+}
+
+func (p *binaryPlist) object(ref uint64, depth int) (any, error) {
+	if ref >= uint64(len(p.offsets)) {
+		return nil, fmt.Errorf("plist: object reference %d out of range", ref)
+	}
+	if depth > maxPlistDepth || p.visiting[ref] {
+		return nil, errors.New("plist: nested too deeply or cyclic")
+	}
+	p.visiting[ref] = true
+	defer delete(p.visiting, ref)
+
+	offset := p.offsets[ref]
+	if offset >= p.objectsEnd {
+		return nil, fmt.Errorf("plist: object offset %d out of range", offset)
+	}
+	marker := p.data[offset]
+	kind, info := marker>>4, marker&0x0f
+	body := offset + 1
+
+	switch kind {
+	case 0x0:
+		switch info {
+		case 0x8:
+			return false, nil
+		case 0x9:
+			return true, nil
+		}
+		return nil, nil
+	case 0x1:
+		size := uint64(1) << info
+		b, err := p.slice(body, size)
+		if err != nil {
+			return nil, err
+		}
+		if size == 16 {
+			// 128-bit integers only appear for values above math.MaxInt64.
+			return readUintN(b[8:]), nil
+		}
+		// Integers of up to four bytes are unsigned, eight-byte ones signed;
+		// both fit int64.
+		return int64(readUintN(b)), nil
+	case 0x2:
+		size := uint64(1) << info
+		b, err := p.slice(body, size)
+		if err != nil {
+			return nil, err
+		}
+		switch size {
+		case 4:
+			return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+		case 8:
+			return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+		}
+		return nil, fmt.Errorf("plist: invalid real size %d", size)
+	case 0x3:
+		b, err := p.slice(body, 8)
+		if err != nil {
+			return nil, err
+		}
+		seconds := math.Float64frombits(binary.BigEndian.Uint64(b))
+		epoch := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+		return epoch.Add(time.Duration(seconds * float64(time.Second))), nil
+	}
+
+	count, body, err := p.count(info, body)
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case 0x4:
+		b, err := p.slice(body, count)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), b...), nil
+	case 0x5:
+		b, err := p.slice(body, count)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case 0x6:
+		b, err := p.slice(body, count*2)
+		if err != nil {
+			return nil, err
+		}
+		units := make([]uint16, count)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(b[i*2:])
+		}
+		return string(utf16.Decode(units)), nil
+	case 0x8:
+		b, err := p.slice(body, uint64(info)+1)
+		if err != nil {
+			return nil, err
+		}
+		return readUintN(b), nil
+	case 0xA, 0xC:
+		refs, err := p.refs(body, count)
+		if err != nil {
+			return nil, err
+		}
+		array := make([]any, 0, len(refs))
+		for _, r := range refs {
+			value, err := p.object(r, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		return array, nil
+	case 0xD:
+		refs, err := p.refs(body, count*2)
+		if err != nil {
+			return nil, err
+		}
+		dict := make(map[string]any, count)
+		for i := uint64(0); i < count; i++ {
+			key, err := p.object(refs[i], depth+1)
+			if err != nil {
+				return nil, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, errors.New("plist: dict key is not a string")
+			}
+			value, err := p.object(refs[count+i], depth+1)
+			if err != nil {
+				return nil, err
+			}
+			dict[name] = value
+		}
+		return dict, nil
+	}
+	return nil, fmt.Errorf("plist: unknown object type 0x%x", marker)
+}
+
+// count decodes the length of a variable-sized object. Lengths of 15 or more
+// are stored as a following integer object.
+func (p *binaryPlist) count(info byte, body uint64) (uint64, uint64, error) {
+	if info != 0xf {
+		return uint64(info), body, nil
+	}
+	b, err := p.slice(body, 1)
+	if err != nil {
+		return 0, 0, err
+	}
+	if b[0]>>4 != 0x1 {
+		return 0, 0, errors.New("plist: invalid length")
+	}
+	size := uint64(1) << (b[0] & 0x0f)
+	n, err := p.slice(body+1, size)
+	if err != nil {
+		return 0, 0, err
+	}
+	return readUintN(n), body + 1 + size, nil
+}
+
+func (p *binaryPlist) refs(body, count uint64) ([]uint64, error) {
+	if count > uint64(len(p.data)) {
+		return nil, errors.New("plist: collection too large")
+	}
+	b, err := p.slice(body, count*uint64(p.refSize))
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]uint64, count)
+	for i := range refs {
+		refs[i] = readUintN(b[i*p.refSize : (i+1)*p.refSize])
+	}
+	return refs, nil
+}
+
+func (p *binaryPlist) slice(start, size uint64) ([]byte, error) {
+	if start > p.objectsEnd || size > p.objectsEnd-start {
+		return nil, errors.New("plist: object extends past the object table")
+	}
+	return p.data[start : start+size], nil
+}
+
+// parseStringsFile decodes an InfoPlist.strings file, which is either a
+// property list or an old-style "key" = "value"; list, in UTF-8 or UTF-16.
+func parseStringsFile(data []byte) (map[string]string, error) {
+	if bytes.HasPrefix(data, []byte("bplist00")) || bytes.HasPrefix(bytes.TrimSpace(data), []byte("<?xml")) {
+		value, err := parsePlist(data)
+		if err != nil {
+			return nil, err
+		}
+		dict, _ := value.(map[string]any)
+		result := make(map[string]string, len(dict))
+		for key := range dict {
+			result[key] = plistString(dict, key)
+		}
+		return result, nil
+	}
+
+	text := decodeStringsText(data)
+	result := make(map[string]string)
+	s := stringsScanner{text: text}
+	for {
+		key, ok, err := s.token()
+		if err != nil {
+			return result, err
+		}
+		if !ok {
+			return result, nil
+		}
+		if err := s.expect('='); err != nil {
+			return result, err
+		}
+		value, ok, err := s.token()
+		if err != nil {
+			return result, err
+		}
+		if !ok {
+			return result, errors.New("strings: missing value")
+		}
+		if err := s.expect(';'); err != nil {
+			return result, err
+		}
+		result[key] = value
+	}
+}
+
+// decodeStringsText converts UTF-16 text, recognised by its byte order mark,
+// to a string. Other input is taken as UTF-8.
+func decodeStringsText(data []byte) string {
+	var order binary.ByteOrder
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		order = binary.BigEndian
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		order = binary.LittleEndian
+	default:
+		return strings.TrimPrefix(string(data), "\ufeff")
+	}
+	data = data[2:]
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[i*2:])
+	}
+	return string(utf16.Decode(units))
+}
+
+type stringsScanner struct {
+	text string
+	pos  int
+}
+
+// skip moves past whitespace and comments.
+func (s *stringsScanner) skip() {
+	for s.pos < len(s.text) {
+		rest := s.text[s.pos:]
+		switch {
+		case rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\n' || rest[0] == '\r':
+			s.pos++
+		case strings.HasPrefix(rest, "//"):
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				s.pos = len(s.text)
+			} else {
+				s.pos += end + 1
+			}
+		case strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest[2:], "*/")
+			if end < 0 {
+				s.pos = len(s.text)
+			} else {
+				s.pos += end + 4
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (s *stringsScanner) expect(c byte) error {
+	s.skip()
+	if s.pos >= len(s.text) || s.text[s.pos] != c {
+		return fmt.Errorf("strings: expected %q at offset %d", c, s.pos)
+	}
+	s.pos++
+	return nil
+}
+
+// token reads a quoted string or a bare word.
+func (s *stringsScanner) token() (string, bool, error) {
+	s.skip()
+	if s.pos >= len(s.text) {
+		return "", false, nil
+	}
+	if s.text[s.pos] != '"' {
+		start := s.pos
+		for s.pos < len(s.text) && !strings.ContainsRune(" \t\r\n=;\"", rune(s.text[s.pos])) {
+			s.pos++
+		}
+		if start == s.pos {
+			return "", false, fmt.Errorf("strings: unexpected %q at offset %d", s.text[s.pos], s.pos)
+		}
+		return s.text[start:s.pos], true, nil
+	}
+
+	s.pos++
+	var b strings.Builder
+	for s.pos < len(s.text) {
+		c := s.text[s.pos]
+		switch c {
+		case '"':
+			s.pos++
+			return b.String(), true, nil
+		case '\\':
+			if s.pos+1 >= len(s.text) {
+				return "", false, errors.New("strings: unterminated escape")
+			}
+			s.pos++
+			switch e := s.text[s.pos]; e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case 'U', 'u':
+				if s.pos+4 < len(s.text) {
+					if n, err := strconv.ParseUint(s.text[s.pos+1:s.pos+5], 16, 16); err == nil {
+						b.WriteRune(rune(n))
+						s.pos += 4
+						break
+					}
+				}
+				b.WriteByte(e)
+			default:
+				b.WriteByte(e)
+			}
+			s.pos++
+		default:
+			r, size := utf8.DecodeRuneInString(s.text[s.pos:])
+			b.WriteRune(r)
+			s.pos += size
+		}
+	}
+	return "", false, errors.New("strings: unterminated string")
+}
+
+func plistString(dict map[string]any, key string) string {
+	s, _ := dict[key].(string)
+	return s
+}
+
+func plistBool(dict map[string]any, key string) bool {
+	switch v := dict[key].(type) {
+	case bool:
+		return v
+	case string:
+		// Some bundles store booleans as strings.
+		return v == "1" || strings.EqualFold(v, "true") || strings.EqualFold(v, "yes")
+	case int64:
+		return v != 0
+	}
+	return false
+}
+
+func plistStrings(dict map[string]any, key string) []string {
+	array, _ := dict[key].([]any)
+	result := make([]string, 0, len(array))
+	for _, item := range array {
+		if s, ok := item.(string); ok {
+			if trimmed := strings.TrimSpace(s); trimmed != "" {
+				result = append(result, trimmed)
+			}
+		}
+	}
+	return result
+}
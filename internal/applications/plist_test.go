@@ -0,0 +1,187 @@
+package applications
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+// testdata/plist holds the same dictionary as an XML and a binary plist.
+var samplePlist = map[string]any{
+	"CFBundleIdentifier":  "com.example.Sample",
+	"CFBundleName":        "Sample",
+	"CFBundleDisplayName": "Sample Grüße",
+	"Count":               int64(42),
+	"Negative":            int64(-7),
+	"Big":                 int64(1 << 40),
+	"Ratio":               0.5,
+	"Enabled":             true,
+	"Disabled":            false,
+	"Built":               time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	"Blob":                []byte("\x00\x01binary"),
+	"Files":               []any{"one.icns", "two.icns", strings.Repeat("a", 20)},
+	"Nested":              map[string]any{"Deep": map[string]any{"Key": "value"}, "Empty": []any(nil)},
+}
+
+func TestParsePlist(t *testing.T) {
+	for _, name := range []string{"sample.xml", "sample.bplist"} {
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", "plist", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := parsePlist(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dict, ok := got.(map[string]any)
+			if !ok {
+				t.Fatalf("parsePlist returned %T", got)
+			}
+			// Empty arrays decode as nil from XML and as an empty slice from
+			// the binary format; both read the same.
+			if nested, ok := dict["Nested"].(map[string]any); ok && len(nested["Empty"].([]any)) == 0 {
+				nested["Empty"] = []any(nil)
+			}
+			for key, want := range samplePlist {
+				if !reflect.DeepEqual(dict[key], want) {
+					t.Errorf("%s = %#v, want %#v", key, dict[key], want)
+				}
+			}
+			if len(dict) != len(samplePlist) {
+				t.Errorf("got %d keys, want %d", len(dict), len(samplePlist))
+			}
+		})
+	}
+}
+
+func TestParseXMLPlistErrors(t *testing.T) {
+	tests := []struct {
+		name, data string
+	}{
+		{"truncated", `<plist><dict><key>A</key><string>b`},
+		{"value without key", `<plist><dict><string>b</string></dict></plist>`},
+		{"bad integer", `<plist><integer>x</integer></plist>`},
+		{"bad date", `<plist><date>yesterday</date></plist>`},
+		{"unknown element", `<plist><color>red</color></plist>`},
+		{"too deep", "<plist>" + strings.Repeat("<array>", maxPlistDepth+2) + "</plist>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := parsePlist([]byte(tt.data)); err == nil {
+				t.Errorf("parsePlist(%q) = %#v, want an error", tt.data, got)
+			}
+		})
+	}
+}
+
+func TestParseBinaryPlistErrors(t *testing.T) {
+	sample, err := os.ReadFile(filepath.Join("testdata", "plist", "sample.bplist"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	trailer := len(sample) - 32
+	offsetSize := int(sample[trailer+6])
+	tableOffset := int(binary.BigEndian.Uint64(sample[trailer+24:]))
+
+	tests := []struct {
+		name   string
+		damage func(data []byte) []byte
+	}{
+		{"truncated", func(data []byte) []byte { return data[:30] }},
+		{"offset size zero", func(data []byte) []byte { data[trailer+6] = 0; return data }},
+		{"ref size too large", func(data []byte) []byte { data[trailer+7] = 9; return data }},
+		{"no objects", func(data []byte) []byte {
+			binary.BigEndian.PutUint64(data[trailer+8:], 0)
+			return data
+		}},
+		{"too many objects", func(data []byte) []byte {
+			binary.BigEndian.PutUint64(data[trailer+8:], 1<<40)
+			return data
+		}},
+		{"top object out of range", func(data []byte) []byte {
+			binary.BigEndian.PutUint64(data[trailer+16:], binary.BigEndian.Uint64(data[trailer+8:]))
+			return data
+		}},
+		{"offset table past the trailer", func(data []byte) []byte {
+			binary.BigEndian.PutUint64(data[trailer+24:], uint64(trailer+1))
+			return data
+		}},
+		{"object offset out of range", func(data []byte) []byte {
+			// Point the top object into the offset table.
+			for i := 0; i < offsetSize; i++ {
+				data[tableOffset+i] = 0xFF
+			}
+			return data
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := tt.damage(append([]byte(nil), sample...))
+			if got, err := parsePlist(data); err == nil {
+				t.Errorf("parsePlist accepted the damaged plist: %#v", got)
+			}
+		})
+	}
+}
+
+func TestParseBinaryPlistCycle(t *testing.T) {
+	// An array whose only element is itself.
+	data := []byte("bplist00")
+	data = append(data, 0xA1, 0x00) // array of one ref to object 0
+	tableOffset := len(data)
+	data = append(data, 8) // object 0 at offset 8
+	trailer := make([]byte, 32)
+	trailer[6], trailer[7] = 1, 1
+	binary.BigEndian.PutUint64(trailer[8:], 1)
+	binary.BigEndian.PutUint64(trailer[24:], uint64(tableOffset))
+	data = append(data, trailer...)
+
+	if _, err := parsePlist(data); err == nil {
+		t.Error("parsePlist accepted a cyclic plist")
+	}
+}
+
+func TestParseStringsFile(t *testing.T) {
+	text := "/* comment */\n\"CFBundleDisplayName\" = \"Grüße \\\"App\\\"\";\n// line comment\nCFBundleName = Bare;\n\"Escaped\" = \"a\\nb \\U00e9\";\n"
+	want := map[string]string{
+		"CFBundleDisplayName": `Grüße "App"`,
+		"CFBundleName":        "Bare",
+		"Escaped":             "a\nb é",
+	}
+
+	utf16le := []byte{0xFF, 0xFE}
+	utf16be := []byte{0xFE, 0xFF}
+	for _, unit := range utf16.Encode([]rune(text)) {
+		utf16le = binary.LittleEndian.AppendUint16(utf16le, unit)
+		utf16be = binary.BigEndian.AppendUint16(utf16be, unit)
+	}
+	encodings := map[string][]byte{
+		"UTF-8":     []byte(text),
+		"UTF-8 BOM": append([]byte("\ufeff"), text...),
+		"UTF-16LE":  utf16le,
+		"UTF-16BE":  utf16be,
+	}
+	for name, data := range encodings {
+		t.Run(name, func(t *testing.T) {
+			got, err := parseStringsFile(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("parseStringsFile = %q, want %q", got, want)
+			}
+		})
+	}
+
+	for _, bad := range []string{`"a" = "b"`, `"a" "b";`, `"a" = "b`, `= "b";`} {
+		if _, err := parseStringsFile([]byte(bad)); err == nil {
+			t.Errorf("parseStringsFile(%q) succeeded", bad)
+		}
+	}
+}
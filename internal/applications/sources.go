@@ -0,0 +1,183 @@
+package applications
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Source names used to tag applications.
+const (
+	SourceDesktop  = "desktop"
+	SourceFlatpak  = "flatpak"
+	SourceSnap     = "snap"
+	SourceAppImage = "appimage"
+	SourceMacOS    = "macos"
+	// SourcePath tags commands found on $PATH and command lines typed by the
+	// user.
+	SourcePath = "path"
+)
+
+// Source is a place applications are discovered from. A plain Source
+// contributes directories of .desktop files; all of those share one index so
+// desktop file IDs shadow each other across sources, in the order they are
+// listed. Sources that also implement Lister produce their applications
+// directly, and their Dirs are only watched for changes.
+type Source interface {
+	// Name tags the applications the source produces, e.g. "flatpak".
+	Name() string
+	Dirs() []string
+}
+
+// Lister is implemented by sources whose applications are not desktop entries.
+type Lister interface {
+	Source
+	List() ([]Application, error)
+}
+
+// sources is fixed at startup; the watcher goroutine reads it without
+// locking.
+var sources = defaultSources()
+
+func defaultSources() []Source {
+	if runtime.GOOS == "darwin" {
+		return []Source{bundleSource{}, &pathSource{}}
+	}
+	return []Source{desktopSource{}, flatpakSource{}, snapSource{}, appImageSource{}, &pathSource{}}
+}
+
+// desktopRoots returns the application directories of every desktop entry
+// source in precedence order, with the name of the source owning each. A
+// directory listed by several sources, such as a Flatpak export directory that
+// is also in XDG_DATA_DIRS, keeps its XDG position but takes the more specific
+// tag.
+func desktopRoots() (dirs, names []string) {
+	positions := make(map[string]int)
+	for _, source := range sources {
+		if _, ok := source.(Lister); ok {
+			continue
+		}
+		for _, dir := range source.Dirs() {
+			dir = filepath.Clean(dir)
+			if i, ok := positions[dir]; ok {
+				if names[i] == SourceDesktop {
+					names[i] = source.Name()
+				}
+				continue
+			}
+			positions[dir] = len(dirs)
+			dirs = append(dirs, dir)
+			names = append(names, source.Name())
+		}
+	}
+	return dirs, names
+}
+
+func listSource(lister Lister) ([]Application, error) {
+	apps, err := lister.List()
+	for i := range apps {
+		if apps[i].Source == "" {
+			apps[i].Source = lister.Name()
+		}
+	}
+	if err != nil {
+		err = fmt.Errorf("%s: %w", lister.Name(), err)
+	}
+	return apps, err
+}
+
+// desktopSource covers the XDG application directories.
+type desktopSource struct{}
+
+func (desktopSource) Name() string { return SourceDesktop }
+
+func (desktopSource) Dirs() []string { return desktopDirs() }
+
+// flatpakSource covers the per-user and system-wide Flatpak exports.
+type flatpakSource struct{}
+
+func (flatpakSource) Name() string { return SourceFlatpak }
+
+func (flatpakSource) Dirs() []string {
+	var dirs []string
+	if dataHome := dataHomeDir(); dataHome != "" {
+		dirs = append(dirs, filepath.Join(dataHome, "flatpak/exports/share/applications"))
+	}
+	return append(dirs, "/var/lib/flatpak/exports/share/applications")
+}
+
+// snapSource covers the desktop files snapd generates for installed snaps.
+type snapSource struct{}
+
+func (snapSource) Name() string { return SourceSnap }
+
+func (snapSource) Dirs() []string {
+	return []string{"/var/lib/snapd/desktop/applications"}
+}
+
+// appImageSource lists AppImages dropped into the conventional directories.
+type appImageSource struct{}
+
+func (appImageSource) Name() string { return SourceAppImage }
+
+func (appImageSource) Dirs() []string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	return []string{filepath.Join(home, "Applications"), filepath.Join(home, "AppImages")}
+}
+
+func (s appImageSource) List() ([]Application, error) {
+	env := currentDesktopEnv()
+	var (
+		apps []Application
+		errs []error
+	)
+	for _, dir := range s.Dirs() {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, fmt.Errorf("read dir %s: %w", dir, err))
+			}
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".appimage") {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			app, err := parseAppImage(path, env)
+			if err != nil {
+				if errors.Is(err, errSkipApplication) {
+					continue
+				}
+				errs = append(errs, fmt.Errorf("appimage %s: %w", path, err))
+			}
+			apps = append(apps, app)
+		}
+	}
+	return apps, errors.Join(errs...)
+}
+
+// bundleSource lists macOS application bundles.
+type bundleSource struct{}
+
+func (bundleSource) Name() string { return SourceMacOS }
+
+func (bundleSource) Dirs() []string { return darwinRoots() }
+
+func (bundleSource) List() ([]Application, error) { return listDarwin() }
+
+func dataHomeDir() string {
+	if dataHome := os.Getenv("XDG_DATA_HOME"); dataHome != "" {
+		return dataHome
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".local/share")
+	}
+	return ""
+}
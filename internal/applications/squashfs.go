@@ -0,0 +1,438 @@
+package applications
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// squashFS is a minimal read-only SquashFS 4.0 reader, just enough to pull
+// the desktop file and icon out of an AppImage without mounting or executing
+// it. Only gzip-compressed and uncompressed images are supported.
+type squashFS struct {
+	r    io.ReaderAt
+	base int64
+	sb   squashSuperblock
+}
+
+type squashSuperblock struct {
+	Magic        uint32
+	InodeCount   uint32
+	ModTime      uint32
+	BlockSize    uint32
+	FragCount    uint32
+	Compression  uint16
+	BlockLog     uint16
+	Flags        uint16
+	IDCount      uint16
+	VersionMajor uint16
+	VersionMinor uint16
+	RootInode    uint64
+	BytesUsed    uint64
+	IDTable      uint64
+	XattrTable   uint64
+	InodeTable   uint64
+	DirTable     uint64
+	FragTable    uint64
+	ExportTable  uint64
+}
+
+const (
+	squashMagic          = 0x73717368
+	squashGzip           = 1
+	squashMetadataSize   = 8192
+	squashNoFragment     = 0xFFFFFFFF
+	squashUncompressed   = 1 << 24
+	squashMetaUncompress = 0x8000
+)
+
+// Inode types used by the reader.
+const (
+	squashDir        = 1
+	squashFile       = 2
+	squashSymlink    = 3
+	squashExtDir     = 8
+	squashExtFile    = 9
+	squashExtSymlink = 10
+)
+
+// errUnsupportedSquashFS marks images that are valid but use a compression the
+// reader does not implement.
+var errUnsupportedSquashFS = errors.New("unsupported squashfs compression")
+
+type squashInode struct {
+	kind uint16
+	// Directories.
+	dirBlock  uint32
+	dirOffset uint16
+	dirSize   uint32
+	// Regular files.
+	blocksStart uint64
+	fragment    uint32
+	fragOffset  uint32
+	fileSize    uint64
+	blockSizes  []uint32
+	// Symlinks.
+	target string
+}
+
+type squashDirEntry struct {
+	name  string
+	inode uint64
+}
+
+func openSquashFS(r io.ReaderAt, offset int64) (*squashFS, error) {
+	fs := &squashFS{r: r, base: offset}
+	header := io.NewSectionReader(r, offset, 96)
+	if err := binary.Read(header, binary.LittleEndian, &fs.sb); err != nil {
+		return nil, fmt.Errorf("read superblock: %w", err)
+	}
+	if fs.sb.Magic != squashMagic {
+		return nil, fmt.Errorf("not a squashfs image")
+	}
+	if fs.sb.VersionMajor != 4 {
+		return nil, fmt.Errorf("squashfs version %d.%d", fs.sb.VersionMajor, fs.sb.VersionMinor)
+	}
+	// Every file's block count is derived from the block size, so a damaged
+	// superblock must not get further.
+	if bs := fs.sb.BlockSize; bs < 4<<10 || bs > 1<<20 || bs&(bs-1) != 0 || fs.sb.BlockLog >= 32 || 1<<fs.sb.BlockLog != bs {
+		return nil, fmt.Errorf("invalid squashfs block size %d (log %d)", fs.sb.BlockSize, fs.sb.BlockLog)
+	}
+	if fs.sb.Compression != squashGzip {
+		return nil, errUnsupportedSquashFS
+	}
+	return fs, nil
+}
+
+// rootEntries lists the top-level directory of the image.
+func (fs *squashFS) rootEntries() ([]squashDirEntry, error) {
+	root, err := fs.inode(fs.sb.RootInode)
+	if err != nil {
+		return nil, err
+	}
+	return fs.readDir(root)
+}
+
+// readFile returns the contents of the named top-level file, following one
+// level of symlinks. Files larger than limit are rejected.
+func (fs *squashFS) readFile(entries []squashDirEntry, name string, limit uint64) ([]byte, error) {
+	for hops := 0; hops < 4; hops++ {
+		var ref uint64
+		found := false
+		for _, entry := range entries {
+			if entry.name == name {
+				ref, found = entry.inode, true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%s not found", name)
+		}
+		node, err := fs.inode(ref)
+		if err != nil {
+			return nil, err
+		}
+		switch node.kind {
+		case squashSymlink, squashExtSymlink:
+			name = node.target
+			continue
+		case squashFile, squashExtFile:
+			if node.fileSize > limit {
+				return nil, fmt.Errorf("%s is too large", name)
+			}
+			return fs.readData(node)
+		default:
+			return nil, fmt.Errorf("%s is not a regular file", name)
+		}
+	}
+	return nil, fmt.Errorf("too many symlinks resolving %s", name)
+}
+
+func (fs *squashFS) inode(ref uint64) (squashInode, error) {
+	mr, err := fs.metaReader(int64(fs.sb.InodeTable)+int64(ref>>16), uint16(ref&0xFFFF))
+	if err != nil {
+		return squashInode{}, err
+	}
+	var header struct {
+		Kind        uint16
+		Permissions uint16
+		UID         uint16
+		GID         uint16
+		ModTime     uint32
+		Number      uint32
+	}
+	if err := binary.Read(mr, binary.LittleEndian, &header); err != nil {
+		return squashInode{}, err
+	}
+
+	node := squashInode{kind: header.Kind}
+	switch header.Kind {
+	case squashDir:
+		var d struct {
+			BlockIndex uint32
+			LinkCount  uint32
+			FileSize   uint16
+			Offset     uint16
+			Parent     uint32
+		}
+		if err := binary.Read(mr, binary.LittleEndian, &d); err != nil {
+			return node, err
+		}
+		node.dirBlock, node.dirOffset, node.dirSize = d.BlockIndex, d.Offset, uint32(d.FileSize)
+	case squashExtDir:
+		var d struct {
+			LinkCount  uint32
+			FileSize   uint32
+			BlockIndex uint32
+			Parent     uint32
+			IndexCount uint16
+			Offset     uint16
+			Xattr      uint32
+		}
+		if err := binary.Read(mr, binary.LittleEndian, &d); err != nil {
+			return node, err
+		}
+		node.dirBlock, node.dirOffset, node.dirSize = d.BlockIndex, d.Offset, d.FileSize
+	case squashFile:
+		var f struct {
+			BlocksStart uint32
+			Fragment    uint32
+			Offset      uint32
+			FileSize    uint32
+		}
+		if err := binary.Read(mr, binary.LittleEndian, &f); err != nil {
+			return node, err
+		}
+		node.blocksStart, node.fragment, node.fragOffset, node.fileSize = uint64(f.BlocksStart), f.Fragment, f.Offset, uint64(f.FileSize)
+	case squashExtFile:
+		var f struct {
+			BlocksStart uint64
+			FileSize    uint64
+			Sparse      uint64
+			LinkCount   uint32
+			Fragment    uint32
+			Offset      uint32
+			Xattr       uint32
+		}
+		if err := binary.Read(mr, binary.LittleEndian, &f); err != nil {
+			return node, err
+		}
+		node.blocksStart, node.fragment, node.fragOffset, node.fileSize = f.BlocksStart, f.Fragment, f.Offset, f.FileSize
+	case squashSymlink, squashExtSymlink:
+		var l struct {
+			LinkCount  uint32
+			TargetSize uint32
+		}
+		if err := binary.Read(mr, binary.LittleEndian, &l); err != nil {
+			return node, err
+		}
+		if l.TargetSize > 4096 {
+			return node, fmt.Errorf("symlink target too long")
+		}
+		target := make([]byte, l.TargetSize)
+		if _, err := io.ReadFull(mr, target); err != nil {
+			return node, err
+		}
+		node.target = string(target)
+		return node, nil
+	default:
+		return node, nil
+	}
+
+	if node.kind == squashFile || node.kind == squashExtFile {
+		blockSize := uint64(fs.sb.BlockSize)
+		count := node.fileSize / blockSize
+		if node.fragment == squashNoFragment && node.fileSize%blockSize != 0 {
+			count++
+		}
+		if count > 1<<16 {
+			return node, fmt.Errorf("file too large")
+		}
+		node.blockSizes = make([]uint32, count)
+		if err := binary.Read(mr, binary.LittleEndian, node.blockSizes); err != nil {
+			return node, err
+		}
+	}
+	return node, nil
+}
+
+func (fs *squashFS) readDir(dir squashInode) ([]squashDirEntry, error) {
+	if dir.kind != squashDir && dir.kind != squashExtDir {
+		return nil, fmt.Errorf("not a directory")
+	}
+	mr, err := fs.metaReader(int64(fs.sb.DirTable)+int64(dir.dirBlock), dir.dirOffset)
+	if err != nil {
+		return nil, err
+	}
+	// The stored size includes three bytes for the implicit . and .. entries.
+	remaining := int64(dir.dirSize) - 3
+	var entries []squashDirEntry
+	for remaining > 0 {
+		var header struct {
+			Count  uint32
+			Start  uint32
+			Number uint32
+		}
+		if err := binary.Read(mr, binary.LittleEndian, &header); err != nil {
+			return nil, err
+		}
+		remaining -= 12
+		for i := uint32(0); i <= header.Count && remaining > 0; i++ {
+			var entry struct {
+				Offset   uint16
+				InodeOff int16
+				Kind     uint16
+				NameSize uint16
+			}
+			if err := binary.Read(mr, binary.LittleEndian, &entry); err != nil {
+				return nil, err
+			}
+			name := make([]byte, int(entry.NameSize)+1)
+			if _, err := io.ReadFull(mr, name); err != nil {
+				return nil, err
+			}
+			remaining -= 8 + int64(len(name))
+			entries = append(entries, squashDirEntry{
+				name:  string(name),
+				inode: uint64(header.Start)<<16 | uint64(entry.Offset),
+			})
+		}
+	}
+	return entries, nil
+}
+
+func (fs *squashFS) readData(node squashInode) ([]byte, error) {
+	data := make([]byte, 0, node.fileSize)
+	pos := fs.base + int64(node.blocksStart)
+	for _, size := range node.blockSizes {
+		onDisk := size &^ squashUncompressed
+		if onDisk == 0 {
+			data = append(data, make([]byte, fs.sb.BlockSize)...)
+			continue
+		}
+		block, err := fs.readBlock(pos, onDisk, size&squashUncompressed == 0)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, block...)
+		pos += int64(onDisk)
+	}
+	if node.fragment != squashNoFragment {
+		fragment, err := fs.readFragment(node.fragment)
+		if err != nil {
+			return nil, err
+		}
+		tail := node.fileSize % uint64(fs.sb.BlockSize)
+		end := uint64(node.fragOffset) + tail
+		if end > uint64(len(fragment)) {
+			return nil, fmt.Errorf("fragment out of range")
+		}
+		data = append(data, fragment[node.fragOffset:end]...)
+	}
+	if uint64(len(data)) > node.fileSize {
+		data = data[:node.fileSize]
+	}
+	return data, nil
+}
+
+func (fs *squashFS) readFragment(index uint32) ([]byte, error) {
+	var pointer uint64
+	table := io.NewSectionReader(fs.r, fs.base+int64(fs.sb.FragTable)+int64(index/512)*8, 8)
+	if err := binary.Read(table, binary.LittleEndian, &pointer); err != nil {
+		return nil, err
+	}
+	mr, err := fs.metaReaderAt(fs.base+int64(pointer), uint16(index%512)*16)
+	if err != nil {
+		return nil, err
+	}
+	var entry struct {
+		Start  uint64
+		Size   uint32
+		Unused uint32
+	}
+	if err := binary.Read(mr, binary.LittleEndian, &entry); err != nil {
+		return nil, err
+	}
+	onDisk := entry.Size &^ squashUncompressed
+	return fs.readBlock(fs.base+int64(entry.Start), onDisk, entry.Size&squashUncompressed == 0)
+}
+
+func (fs *squashFS) readBlock(pos int64, size uint32, compressed bool) ([]byte, error) {
+	if size > 1<<20 {
+		return nil, fmt.Errorf("block too large")
+	}
+	raw := make([]byte, size)
+	if _, err := fs.r.ReadAt(raw, pos); err != nil {
+		return nil, err
+	}
+	if !compressed {
+		return raw, nil
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	// Metadata blocks are 8 KiB even in images with 4 KiB data blocks.
+	return io.ReadAll(io.LimitReader(zr, int64(max(fs.sb.BlockSize, squashMetadataSize))+1))
+}
+
+// metaReader returns a reader over the metadata stream starting at the block
+// located start bytes into the image, skipping offset decompressed bytes.
+func (fs *squashFS) metaReader(start int64, offset uint16) (*squashMetaReader, error) {
+	return fs.metaReaderAt(fs.base+start, offset)
+}
+
+func (fs *squashFS) metaReaderAt(pos int64, offset uint16) (*squashMetaReader, error) {
+	mr := &squashMetaReader{fs: fs, next: pos}
+	if err := mr.fill(); err != nil {
+		return nil, err
+	}
+	if int(offset) > len(mr.buf) {
+		return nil, fmt.Errorf("metadata offset out of range")
+	}
+	mr.buf = mr.buf[offset:]
+	return mr, nil
+}
+
+// squashMetaReader reads consecutive metadata blocks as one stream.
+type squashMetaReader struct {
+	fs   *squashFS
+	next int64
+	buf  []byte
+}
+
+func (mr *squashMetaReader) fill() error {
+	var header uint16
+	if err := binary.Read(io.NewSectionReader(mr.fs.r, mr.next, 2), binary.LittleEndian, &header); err != nil {
+		return err
+	}
+	size := uint32(header &^ squashMetaUncompress)
+	if size > squashMetadataSize {
+		return fmt.Errorf("metadata block too large")
+	}
+	block, err := mr.fs.readBlock(mr.next+2, size, header&squashMetaUncompress == 0)
+	if err != nil {
+		return err
+	}
+	mr.buf = block
+	mr.next += 2 + int64(size)
+	return nil
+}
+
+func (mr *squashMetaReader) Read(p []byte) (int, error) {
+	if len(mr.buf) == 0 {
+		if err := mr.fill(); err != nil {
+			return 0, err
+		}
+		if len(mr.buf) == 0 {
+			return 0, io.ErrUnexpectedEOF
+		}
+	}
+	n := copy(p, mr.buf)
+	mr.buf = mr.buf[n:]
+	return n, nil
+}
@@ -0,0 +1,142 @@
+package applications
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testdata/tiny.squashfs is a gzip-compressed SquashFS 4.0 image with 4 KiB
+// blocks. Its root holds app.desktop in a data block, myapp.png in a
+// fragment and .DirIcon, a symlink to myapp.png.
+func readTinySquashFS(t *testing.T) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "tiny.squashfs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// writeAppImage writes image behind a 64-bit ELF header without section
+// headers, which puts the image right after the header.
+func writeAppImage(t *testing.T, name string, image []byte) string {
+	t.Helper()
+	elf := make([]byte, 64)
+	copy(elf, "\x7fELF\x02\x01\x01")
+	binary.LittleEndian.PutUint64(elf[40:], 64)
+	binary.LittleEndian.PutUint16(elf[58:], 64)
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, append(elf, image...), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSquashFSReadFile(t *testing.T) {
+	fs, err := openSquashFS(bytes.NewReader(readTinySquashFS(t)), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := fs.rootEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.name)
+	}
+	if got, want := strings.Join(names, " "), ".DirIcon app.desktop myapp.png"; got != want {
+		t.Errorf("root entries = %q, want %q", got, want)
+	}
+
+	desktop, err := fs.readFile(entries, "app.desktop", 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(desktop, []byte("Name=Tiny App\n")) {
+		t.Errorf("app.desktop = %q", desktop)
+	}
+
+	icon, err := fs.readFile(entries, ".DirIcon", 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sniffIconExtension(icon) != ".png" {
+		t.Errorf(".DirIcon does not resolve to the PNG fragment: %q", icon)
+	}
+
+	if _, err := fs.readFile(entries, "app.desktop", 16); err == nil {
+		t.Error("readFile ignored the size limit")
+	}
+	if _, err := fs.readFile(entries, "missing", 1<<20); err == nil {
+		t.Error("readFile found a missing file")
+	}
+}
+
+func TestOpenSquashFSRejectsBadSuperblock(t *testing.T) {
+	tests := []struct {
+		name   string
+		offset int // into the superblock
+		value  any
+	}{
+		{"magic", 0, uint32(0x12345678)},
+		{"zero block size", 12, uint32(0)},
+		{"block size not a power of two", 12, uint32(6000)},
+		{"block size too small", 12, uint32(2048)},
+		{"block size too large", 12, uint32(2 << 20)},
+		{"block log mismatch", 22, uint16(13)},
+		{"version", 28, uint16(3)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			image := readTinySquashFS(t)
+			var field bytes.Buffer
+			binary.Write(&field, binary.LittleEndian, tt.value)
+			copy(image[tt.offset:], field.Bytes())
+			if _, err := openSquashFS(bytes.NewReader(image), 0); err == nil {
+				t.Error("openSquashFS accepted the damaged superblock")
+			}
+		})
+	}
+}
+
+func TestParseAppImage(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	path := writeAppImage(t, "Tiny.AppImage", readTinySquashFS(t))
+
+	app, err := parseAppImage(path, desktopEnv{locales: []string{"de"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.Name != "Winzige App" || app.UntranslatedName != "Tiny App" {
+		t.Errorf("names = %q, %q", app.Name, app.UntranslatedName)
+	}
+	if want := `"` + path + `" --flag %U`; app.Exec != want {
+		t.Errorf("Exec = %q, want %q", app.Exec, want)
+	}
+	if app.Source != SourceAppImage {
+		t.Errorf("Source = %q", app.Source)
+	}
+	if filepath.Ext(app.IconPath) != ".png" || !fileExists(app.IconPath) {
+		t.Errorf("IconPath = %q, want an extracted PNG", app.IconPath)
+	}
+}
+
+func TestParseAppImageDamaged(t *testing.T) {
+	image := readTinySquashFS(t)
+	binary.LittleEndian.PutUint32(image[12:], 0)
+	path := writeAppImage(t, "Broken.AppImage", image)
+
+	app, err := parseAppImage(path, desktopEnv{})
+	if err == nil {
+		t.Error("parseAppImage accepted a zero block size")
+	}
+	if app.Name != "Broken" {
+		t.Errorf("fallback name = %q, want Broken", app.Name)
+	}
+}
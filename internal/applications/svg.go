@@ -0,0 +1,41 @@
+package applications
+
+import (
+	"bytes"
+	"errors"
+	"image"
+
+	"github.com/fyne-io/oksvg"
+	"github.com/srwiley/rasterx"
+)
+
+// rasterizeSVG renders an SVG icon into a size×size image, centred and with
+// its aspect ratio kept.
+func rasterizeSVG(data []byte, size int) (img image.Image, err error) {
+	icon, err := oksvg.ReadIconStream(bytes.NewReader(data), oksvg.IgnoreErrorMode)
+	if err != nil {
+		return nil, err
+	}
+	w, h := icon.ViewBox.W, icon.ViewBox.H
+	if w <= 0 || h <= 0 {
+		return nil, errors.New("svg: missing view box")
+	}
+	dw, dh := float64(size), float64(size)
+	if w > h {
+		dh = float64(size) * h / w
+	} else if h > w {
+		dw = float64(size) * w / h
+	}
+	icon.SetTarget((float64(size)-dw)/2, (float64(size)-dh)/2, dw, dh)
+
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	scanner := rasterx.NewScannerGV(size, size, dst, dst.Bounds())
+	// The renderer panics on some malformed paths.
+	defer func() {
+		if r := recover(); r != nil {
+			img, err = nil, errors.New("svg: could not render image")
+		}
+	}()
+	icon.Draw(rasterx.NewDasher(size, size, scanner), 1)
+	return dst, nil
+}
@@ -0,0 +1,82 @@
+package applications
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// terminalPlaceholder marks where the wrapped command goes in a terminal
+// template. Templates without it get the command appended.
+const terminalPlaceholder = "{cmd}"
+
+// knownTerminals lists common emulators and the template each needs to run a
+// command, in order of preference.
+var knownTerminals = []struct {
+	binary   string
+	template string
+}{
+	{"x-terminal-emulator", "x-terminal-emulator -e"},
+	{"gnome-terminal", "gnome-terminal --"},
+	{"konsole", "konsole -e"},
+	{"xfce4-terminal", "xfce4-terminal -x"},
+	{"kitty", "kitty"},
+	{"alacritty", "alacritty -e"},
+	{"foot", "foot"},
+	{"wezterm", "wezterm start --"},
+	{"tilix", "tilix -e"},
+	{"terminator", "terminator -x"},
+	{"urxvt", "urxvt -e"},
+	{"xterm", "xterm -e"},
+}
+
+// TerminalCommand wraps argv so it runs inside a terminal emulator. The
+// template is tokenized like an Exec line; when empty, a terminal is detected
+// from $TERMINAL or the emulators installed on PATH.
+func TerminalCommand(template string, argv []string) ([]string, error) {
+	if strings.TrimSpace(template) == "" {
+		template = DetectTerminal()
+	}
+	if template == "" {
+		return nil, fmt.Errorf("no terminal emulator found; set launch.terminal in the config")
+	}
+	tokens, err := splitExec(template)
+	if err != nil {
+		return nil, fmt.Errorf("terminal template: %w", err)
+	}
+
+	wrapped := make([]string, 0, len(tokens)+len(argv))
+	replaced := false
+	for _, tok := range tokens {
+		if tok.text == terminalPlaceholder {
+			wrapped = append(wrapped, argv...)
+			replaced = true
+			continue
+		}
+		wrapped = append(wrapped, tok.text)
+	}
+	if !replaced {
+		wrapped = append(wrapped, argv...)
+	}
+	if len(wrapped) == 0 {
+		return nil, fmt.Errorf("empty terminal template")
+	}
+	return wrapped, nil
+}
+
+// DetectTerminal returns a terminal template for the user's preferred
+// emulator, or an empty string when none is installed.
+func DetectTerminal() string {
+	if terminal := strings.TrimSpace(os.Getenv("TERMINAL")); terminal != "" {
+		if _, err := exec.LookPath(terminal); err == nil {
+			return terminal + " -e"
+		}
+	}
+	for _, candidate := range knownTerminals {
+		if _, err := exec.LookPath(candidate.binary); err == nil {
+			return candidate.template
+		}
+	}
+	return ""
+}
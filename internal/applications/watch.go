@@ -0,0 +1,371 @@
+package applications
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce batches bursts of events, such as a package manager
+// installing many files, into a single update.
+const watchDebounce = 300 * time.Millisecond
+
+// Watcher keeps the application list current by watching the directories of
+// every Source and the icon directories. Changed desktop files are re-parsed
+// individually, Lister sources are re-listed as a whole, and a change to the
+// icon directories re-resolves icons without re-parsing entries.
+type Watcher struct {
+	fs       *fsnotify.Watcher
+	onChange func([]Application)
+	onError  func(error)
+	index    *desktopIndex
+	// listed holds the latest result of each Lister source, keyed by name.
+	listed     map[string][]Application
+	listErrs   map[string]error
+	listerDirs map[string]Lister
+	watched    map[string]struct{}
+	iconDirs   map[string]struct{}
+	done       chan struct{}
+	once       sync.Once
+}
+
+// Watch scans the application directories in the background and calls
+// onChange with the full application list whenever it changes. The initial
+// list is only reported when it differs from the cached snapshot returned by
+// CachedList. When that snapshot was stale, it is reported progressively,
+// once the desktop entries are indexed and again as each Lister source
+// finishes. onError receives the
+// problems found by every scan, the initial one included. Both are called
+// from the watcher's goroutine.
+func Watch(onChange func([]Application), onError func(error)) (*Watcher, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		fs:         fsw,
+		onChange:   onChange,
+		onError:    onError,
+		listed:     make(map[string][]Application),
+		listErrs:   make(map[string]error),
+		listerDirs: make(map[string]Lister),
+		watched:    make(map[string]struct{}),
+		iconDirs:   make(map[string]struct{}),
+		done:       make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+// Close stops watching. onChange is not called after Close returns.
+func (w *Watcher) Close() error {
+	var err error
+	w.once.Do(func() {
+		close(w.done)
+		err = w.fs.Close()
+	})
+	return err
+}
+
+func (w *Watcher) run() {
+	cache, ok := readAppCache()
+	valid := ok && stampsValid(cache.Stamps)
+
+	// Lister sources are slow compared to the desktop index, so they run
+	// concurrently and are merged in as each one finishes.
+	var listers []Lister
+	for _, source := range sources {
+		if lister, ok := source.(Lister); ok {
+			listers = append(listers, lister)
+		}
+	}
+	results := make(chan listResult, len(listers))
+	for _, lister := range listers {
+		go func() {
+			apps, err := listSource(lister)
+			results <- listResult{name: lister.Name(), apps: apps, err: err}
+		}()
+	}
+
+	roots, names := desktopRoots()
+	w.index = newDesktopIndex(roots, names, currentDesktopEnv())
+	w.index.scan()
+	for _, root := range roots {
+		w.watchRoot(root)
+	}
+	for _, dir := range w.index.visitedDirs() {
+		w.add(dir)
+	}
+	// Partial results are only reported when there is no usable snapshot to
+	// show instead; errors are left for the complete list.
+	if !valid && len(listers) > 0 {
+		w.emit(w.snapshot())
+	}
+	for i := range listers {
+		var result listResult
+		select {
+		case <-w.done:
+			return
+		case result = <-results:
+		}
+		w.listed[result.name], w.listErrs[result.name] = result.apps, result.err
+		if !valid && i < len(listers)-1 {
+			w.emit(w.snapshot())
+		}
+	}
+	for _, lister := range listers {
+		for _, dir := range lister.Dirs() {
+			w.watchRoot(dir)
+			w.listerDirs[filepath.Clean(dir)] = lister
+		}
+	}
+	// Resolving icons while listing indexes the themes in use, so the icon
+	// directories are only known after the first listing.
+	apps, err := w.list()
+	w.watchIcons()
+	// A valid cache can still be out of date: editing a desktop file in
+	// place does not change the directory stamps.
+	if !valid || !sameApplications(apps, cache.Apps) {
+		w.emit(apps)
+	}
+	// Problems are reported even when the cached list was current, since
+	// the cache does not remember them.
+	w.report(err)
+
+	var (
+		timer        *time.Timer
+		timerC       <-chan time.Time
+		appsDirty    bool
+		iconsDirty   bool
+		dirtyListers = make(map[string]Lister)
+		restartTimer = func() {
+			if timer == nil {
+				timer = time.NewTimer(watchDebounce)
+			} else {
+				timer.Reset(watchDebounce)
+			}
+			timerC = timer.C
+		}
+	)
+	for {
+		select {
+		case <-w.done:
+			return
+		case event, ok := <-w.fs.Events:
+			if !ok {
+				return
+			}
+			switch w.classify(event.Name) {
+			case watchApps:
+				w.handleAppEvent(event)
+				appsDirty = true
+				restartTimer()
+			case watchLister:
+				lister := w.listerFor(event.Name)
+				if _, ok := w.listerDirs[event.Name]; ok {
+					// The directory itself was created or removed.
+					w.watchRoot(event.Name)
+				}
+				dirtyListers[lister.Name()] = lister
+				appsDirty = true
+				restartTimer()
+			case watchIcons:
+				iconsDirty = true
+				restartTimer()
+			}
+		case _, ok := <-w.fs.Errors:
+			if !ok {
+				return
+			}
+		case <-timerC:
+			timerC = nil
+			if iconsDirty {
+				defaultIconResolver().invalidate()
+				w.index.refreshIcons()
+				for _, source := range sources {
+					if lister, ok := source.(Lister); ok {
+						dirtyListers[lister.Name()] = lister
+					}
+				}
+			}
+			for name, lister := range dirtyListers {
+				w.listed[name], w.listErrs[name] = listSource(lister)
+				delete(dirtyListers, name)
+			}
+			if appsDirty || iconsDirty {
+				apps, err := w.list()
+				if iconsDirty {
+					w.watchIcons()
+				}
+				w.emit(apps)
+				w.report(err)
+			}
+			appsDirty, iconsDirty = false, false
+		}
+	}
+}
+
+type watchKind int
+
+const (
+	watchIgnore watchKind = iota
+	watchApps
+	watchLister
+	watchIcons
+)
+
+func (w *Watcher) classify(path string) watchKind {
+	if w.index.rootFor(path) >= 0 {
+		return watchApps
+	}
+	if w.listerFor(path) != nil {
+		return watchLister
+	}
+	if _, ok := w.iconDirs[filepath.Dir(path)]; ok {
+		return watchIcons
+	}
+	if _, ok := w.iconDirs[path]; ok {
+		return watchIcons
+	}
+	return watchIgnore
+}
+
+// listerFor returns the Lister whose directory holds path, or is path.
+func (w *Watcher) listerFor(path string) Lister {
+	if lister, ok := w.listerDirs[path]; ok {
+		return lister
+	}
+	return w.listerDirs[filepath.Dir(path)]
+}
+
+func (w *Watcher) handleAppEvent(event fsnotify.Event) {
+	root := w.index.rootFor(event.Name)
+	switch {
+	case event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename):
+		w.index.remove(event.Name)
+		w.forget(event.Name)
+	case event.Has(fsnotify.Create) || event.Has(fsnotify.Write) || event.Has(fsnotify.Chmod):
+		info, err := os.Stat(event.Name)
+		if err != nil {
+			return
+		}
+		if !info.IsDir() {
+			w.index.add(root, event.Name)
+			return
+		}
+		w.index.scanDir(root, event.Name)
+		filepath.WalkDir(event.Name, func(path string, entry os.DirEntry, err error) error {
+			if err == nil && entry.IsDir() {
+				w.add(path)
+			}
+			return nil
+		})
+	}
+}
+
+type listResult struct {
+	name string
+	apps []Application
+	err  error
+}
+
+// snapshot returns the applications found so far without touching the cache.
+func (w *Watcher) snapshot() []Application {
+	apps, _ := w.index.applications()
+	for _, source := range sources {
+		if lister, ok := source.(Lister); ok {
+			apps = append(apps, w.listed[lister.Name()]...)
+		}
+	}
+	sortApplications(apps)
+	return apps
+}
+
+// list returns the current applications and refreshes the on-disk cache.
+func (w *Watcher) list() ([]Application, error) {
+	apps, errs := w.index.applications()
+	dirs := w.index.visitedDirs()
+	for _, source := range sources {
+		lister, ok := source.(Lister)
+		if !ok {
+			continue
+		}
+		apps = append(apps, w.listed[lister.Name()]...)
+		if err := w.listErrs[lister.Name()]; err != nil {
+			errs = append(errs, err)
+		}
+		dirs = append(dirs, lister.Dirs()...)
+	}
+	sortApplications(apps)
+	saveAppCache(apps, dirs)
+	flushIconCache()
+	return apps, errors.Join(errs...)
+}
+
+func (w *Watcher) emit(apps []Application) {
+	if w.onChange != nil && !w.closed() {
+		w.onChange(apps)
+	}
+}
+
+func (w *Watcher) report(err error) {
+	if err != nil && w.onError != nil && !w.closed() {
+		w.onError(err)
+	}
+}
+
+func (w *Watcher) closed() bool {
+	select {
+	case <-w.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// watchRoot watches root, or its parent while root does not exist yet so
+// that creating it is noticed.
+func (w *Watcher) watchRoot(root string) {
+	if _, err := os.Stat(root); err == nil {
+		w.add(root)
+		return
+	}
+	w.add(filepath.Dir(root))
+}
+
+func (w *Watcher) watchIcons() {
+	for _, dir := range defaultIconResolver().watchedDirs() {
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		w.iconDirs[dir] = struct{}{}
+		w.add(dir)
+	}
+}
+
+// forget drops path and every directory below it, so they are watched again
+// when recreated. Only the moved directory itself reports a rename, not the
+// directories inside it.
+func (w *Watcher) forget(path string) {
+	prefix := path + string(filepath.Separator)
+	for dir := range w.watched {
+		if dir == path || strings.HasPrefix(dir, prefix) {
+			w.fs.Remove(dir)
+			delete(w.watched, dir)
+		}
+	}
+}
+
+func (w *Watcher) add(dir string) {
+	if _, ok := w.watched[dir]; ok {
+		return
+	}
+	if err := w.fs.Add(dir); err == nil {
+		w.watched[dir] = struct{}{}
+	}
+}
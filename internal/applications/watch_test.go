@@ -0,0 +1,186 @@
+package applications
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// isolateDiscovery points every directory discovery reads at an empty temp
+// dir and returns the user's applications directory.
+func isolateDiscovery(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for key, sub := range map[string]string{
+		"HOME":            "home",
+		"XDG_DATA_HOME":   "data",
+		"XDG_DATA_DIRS":   "system",
+		"XDG_CONFIG_HOME": "config",
+		"XDG_CACHE_HOME":  "cache",
+		"XDG_STATE_HOME":  "state",
+		"PATH":            "bin",
+	} {
+		t.Setenv(key, filepath.Join(dir, sub))
+	}
+	apps := filepath.Join(dir, "data", "applications")
+	if err := os.MkdirAll(apps, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return apps
+}
+
+func TestWatchReportsErrorsWithValidCache(t *testing.T) {
+	apps := isolateDiscovery(t)
+	files := map[string]string{
+		"good.desktop":   "[Desktop Entry]\nType=Application\nName=Good\nExec=good\n",
+		"broken.desktop": "[Desktop Entry]\nType=Application\nName=Broken\nExec=\"broken\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(apps, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	watch := func() (changes chan []Application, errs chan error) {
+		changes, errs = make(chan []Application, 8), make(chan error, 8)
+		w, err := Watch(func(apps []Application) { changes <- apps }, func(err error) { errs <- err })
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { w.Close() })
+		return changes, errs
+	}
+	waitError := func(errs chan error) error {
+		select {
+		case err := <-errs:
+			return err
+		case <-time.After(10 * time.Second):
+			t.Fatal("no error reported")
+			return nil
+		}
+	}
+
+	// Without a cache the list is reported, then the problem.
+	changes, errs := watch()
+	if err := waitError(errs); !strings.Contains(err.Error(), "broken.desktop") {
+		t.Errorf("error = %v, want one naming broken.desktop", err)
+	}
+	var last []Application
+	for len(changes) > 0 {
+		last = <-changes
+	}
+	if len(last) != 1 || last[0].Name != "Good" {
+		t.Errorf("applications = %+v, want only Good", last)
+	}
+
+	// With a valid cache the list is not reported again, but the problem
+	// still is.
+	changes, errs = watch()
+	if err := waitError(errs); !strings.Contains(err.Error(), "broken.desktop") {
+		t.Errorf("error = %v, want one naming broken.desktop", err)
+	}
+	if len(changes) > 0 {
+		t.Errorf("the cached list was reported again: %+v", <-changes)
+	}
+}
+
+func TestWatchReportsEntriesEditedInPlace(t *testing.T) {
+	apps := isolateDiscovery(t)
+	path := filepath.Join(apps, "editor.desktop")
+	if err := os.WriteFile(path, []byte("[Desktop Entry]\nType=Application\nName=Editor\nExec=editor\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	watchOnce := func() []Application {
+		changes := make(chan []Application, 8)
+		w, err := Watch(func(apps []Application) { changes <- apps }, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer w.Close()
+		select {
+		case apps := <-changes:
+			// Wait for the complete list.
+			for {
+				select {
+				case apps = <-changes:
+				case <-time.After(time.Second):
+					return apps
+				}
+			}
+		case <-time.After(2 * time.Second):
+			return nil
+		}
+	}
+	if got := watchOnce(); len(got) != 1 || got[0].Name != "Editor" {
+		t.Fatalf("first scan reported %+v, want Editor", got)
+	}
+
+	// Rewriting the file keeps the directory's modification time, so the
+	// cache still looks valid.
+	info, err := os.Stat(apps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("[Desktop Entry]\nType=Application\nName=Text Editor\nExec=editor\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(apps, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if got := watchOnce(); len(got) != 1 || got[0].Name != "Text Editor" {
+		t.Errorf("rescan reported %+v, want the edited entry", got)
+	}
+}
+
+func TestWatchRewatchesRecreatedDirectories(t *testing.T) {
+	apps := isolateDiscovery(t)
+	inner := filepath.Join(apps, "vendor", "inner")
+	if err := os.MkdirAll(inner, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	changes := make(chan []Application, 64)
+	w, err := Watch(func(apps []Application) { changes <- apps }, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	waitFor := func(name string) {
+		t.Helper()
+		deadline := time.After(10 * time.Second)
+		for {
+			select {
+			case list := <-changes:
+				for _, app := range list {
+					if app.Name == name {
+						return
+					}
+				}
+			case <-deadline:
+				t.Fatalf("%s was never reported", name)
+			}
+		}
+	}
+	write := func(name string) {
+		t.Helper()
+		content := "[Desktop Entry]\nType=Application\nName=" + name + "\nExec=true\n"
+		if err := os.WriteFile(filepath.Join(inner, strings.ToLower(name)+".desktop"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("First")
+	waitFor("First")
+
+	// Moving vendor away only reports vendor itself, not vendor/inner.
+	if err := os.Rename(filepath.Join(apps, "vendor"), filepath.Join(t.TempDir(), "vendor")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * watchDebounce)
+	if err := os.MkdirAll(inner, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * watchDebounce)
+	write("Second")
+	waitFor("Second")
+}
@@ -0,0 +1,156 @@
+package applications
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// decodeXPM decodes an XPM3 image: a C array of strings holding the
+// dimensions, the color table and one string per row.
+func decodeXPM(data []byte) (image.Image, error) {
+	lines := xpmStrings(string(data))
+	if len(lines) == 0 {
+		return nil, errors.New("xpm: no image data")
+	}
+	var width, height, ncolors, cpp int
+	if _, err := fmt.Sscan(lines[0], &width, &height, &ncolors, &cpp); err != nil {
+		return nil, fmt.Errorf("xpm: invalid header %q", lines[0])
+	}
+	if width <= 0 || height <= 0 || ncolors <= 0 || cpp <= 0 || width > 4096 || height > 4096 {
+		return nil, fmt.Errorf("xpm: invalid dimensions %q", lines[0])
+	}
+	if len(lines) < 1+ncolors+height {
+		return nil, errors.New("xpm: truncated image")
+	}
+
+	palette := make(map[string]color.NRGBA, ncolors)
+	for _, line := range lines[1 : 1+ncolors] {
+		if len(line) < cpp {
+			return nil, fmt.Errorf("xpm: invalid color %q", line)
+		}
+		value, ok := xpmColorValue(line[cpp:])
+		if !ok {
+			return nil, fmt.Errorf("xpm: invalid color %q", line)
+		}
+		c, ok := parseXPMColor(value)
+		if !ok {
+			// Unknown color names fall back to black rather than failing.
+			c = color.NRGBA{A: 0xff}
+		}
+		palette[line[:cpp]] = c
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y, row := range lines[1+ncolors : 1+ncolors+height] {
+		for x := 0; x < width && (x+1)*cpp <= len(row); x++ {
+			img.SetNRGBA(x, y, palette[row[x*cpp:(x+1)*cpp]])
+		}
+	}
+	return img, nil
+}
+
+// xpmStrings returns the contents of every string literal outside comments.
+func xpmStrings(src string) []string {
+	var result []string
+	for i := 0; i < len(src); i++ {
+		switch {
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return result
+			}
+			i += end + 3
+		case src[i] == '"':
+			end := strings.IndexByte(src[i+1:], '"')
+			if end < 0 {
+				return result
+			}
+			result = append(result, src[i+1:i+1+end])
+			i += end + 1
+		}
+	}
+	return result
+}
+
+// xpmColorValue picks the color for the "c" (color display) key, falling back
+// to the grayscale and monochrome keys.
+func xpmColorValue(spec string) (string, bool) {
+	fields := strings.Fields(spec)
+	values := make(map[string]string)
+	keys := map[string]bool{"c": true, "m": true, "g": true, "g4": true, "s": true}
+	for i := 0; i < len(fields); {
+		key := fields[i]
+		if !keys[key] {
+			i++
+			continue
+		}
+		// Values such as "light grey" may span several words.
+		j := i + 1
+		for j < len(fields) && !keys[fields[j]] {
+			j++
+		}
+		values[key] = strings.Join(fields[i+1:j], " ")
+		i = j
+	}
+	for _, key := range []string{"c", "g", "g4", "m"} {
+		if value, ok := values[key]; ok && value != "" {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// xpmNamedColors covers the X11 color names common in icon files.
+var xpmNamedColors = map[string]color.NRGBA{
+	"black":   {0, 0, 0, 0xff},
+	"white":   {0xff, 0xff, 0xff, 0xff},
+	"red":     {0xff, 0, 0, 0xff},
+	"green":   {0, 0xff, 0, 0xff},
+	"blue":    {0, 0, 0xff, 0xff},
+	"yellow":  {0xff, 0xff, 0, 0xff},
+	"cyan":    {0, 0xff, 0xff, 0xff},
+	"magenta": {0xff, 0, 0xff, 0xff},
+	"gray":    {0xbe, 0xbe, 0xbe, 0xff},
+	"grey":    {0xbe, 0xbe, 0xbe, 0xff},
+	"orange":  {0xff, 0xa5, 0, 0xff},
+}
+
+func parseXPMColor(value string) (color.NRGBA, bool) {
+	lower := strings.ToLower(strings.ReplaceAll(value, " ", ""))
+	if lower == "none" {
+		return color.NRGBA{}, true
+	}
+	if hex, ok := strings.CutPrefix(lower, "#"); ok {
+		// #RGB, #RRGGBB and #RRRRGGGGBBBB keep the most significant digits.
+		if len(hex)%3 != 0 || len(hex) == 0 || len(hex) > 12 {
+			return color.NRGBA{}, false
+		}
+		n := len(hex) / 3
+		var rgb [3]uint8
+		for i := range rgb {
+			v, err := strconv.ParseUint(hex[i*n:(i+1)*n], 16, 64)
+			if err != nil {
+				return color.NRGBA{}, false
+			}
+			// Scale the component to 8 bits.
+			rgb[i] = uint8(v * 255 / (1<<(4*n) - 1))
+		}
+		return color.NRGBA{R: rgb[0], G: rgb[1], B: rgb[2], A: 0xff}, true
+	}
+	if c, ok := xpmNamedColors[lower]; ok {
+		return c, true
+	}
+	for _, prefix := range []string{"gray", "grey"} {
+		if level, ok := strings.CutPrefix(lower, prefix); ok {
+			if n, err := strconv.Atoi(level); err == nil && n >= 0 && n <= 100 {
+				v := uint8((n*255 + 50) / 100)
+				return color.NRGBA{R: v, G: v, B: v, A: 0xff}, true
+			}
+		}
+	}
+	return color.NRGBA{}, false
+}
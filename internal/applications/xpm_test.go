@@ -0,0 +1,77 @@
+package applications
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestDecodeXPM(t *testing.T) {
+	// Two characters per pixel with hex, named, gray and None colors.
+	img, err := decodeXPM(readIconFixture(t, "palette.xpm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w, h := img.Bounds().Dx(), img.Bounds().Dy(); w != 6 || h != 2 {
+		t.Fatalf("size = %dx%d, want 6x2", w, h)
+	}
+	want := []color.NRGBA{
+		{},                    // None
+		{255, 0, 0, 255},      // #FF0000
+		{0, 0, 255, 255},      // blue
+		{128, 128, 128, 255},  // gray50, ignoring the monochrome key
+		{0, 0, 0, 255},        // monochrome key only
+		{0, 0x80, 0xff, 0xff}, // #RRRRGGGGBBBB
+	}
+	for x, c := range want {
+		if got := nrgbaAt(img, x, 0); got != c {
+			t.Errorf("pixel (%d, 0) = %v, want %v", x, got, c)
+		}
+		if got := nrgbaAt(img, len(want)-1-x, 1); got != c {
+			t.Errorf("pixel (%d, 1) = %v, want %v", len(want)-1-x, got, c)
+		}
+	}
+}
+
+func TestDecodeXPMErrors(t *testing.T) {
+	tests := []struct {
+		name, data string
+	}{
+		{"no strings", "/* XPM */"},
+		{"bad header", `"a b c d"`},
+		{"zero size", `"0 1 1 1", ". c red", ""`},
+		{"truncated", `"2 2 1 1", ". c red", ".."`},
+		{"color without value", `"1 1 1 1", ".", "."`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeXPM([]byte(tt.data)); err == nil {
+				t.Error("decodeXPM succeeded")
+			}
+		})
+	}
+}
+
+func TestParseXPMColor(t *testing.T) {
+	tests := []struct {
+		value string
+		want  color.NRGBA
+		ok    bool
+	}{
+		{"None", color.NRGBA{}, true},
+		{"#f00", color.NRGBA{255, 0, 0, 255}, true},
+		{"#00FF00", color.NRGBA{0, 255, 0, 255}, true},
+		{"#FFFF00000000", color.NRGBA{255, 0, 0, 255}, true},
+		{"Light Grey", color.NRGBA{}, false},
+		{"grey100", color.NRGBA{255, 255, 255, 255}, true},
+		{"gray0", color.NRGBA{0, 0, 0, 255}, true},
+		{"gray101", color.NRGBA{}, false},
+		{"#12345", color.NRGBA{}, false},
+		{"#ggg", color.NRGBA{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseXPMColor(tt.value)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("parseXPMColor(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
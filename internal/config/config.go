@@ -1,20 +1,25 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 // Config captures launcher configuration from config.yaml.
 type Config struct {
-	Chat  ChatConfig   `yaml:"chat"`
-	Links []LinkConfig `yaml:"links"`
+	Chat    ChatConfig    `yaml:"chat"`
+	Links   []LinkConfig  `yaml:"links"`
+	Launch  LaunchConfig  `yaml:"launch"`
+	Icons   IconConfig    `yaml:"icons"`
+	History HistoryConfig `yaml:"history"`
 }
 
 // ChatConfig contains AI chat plugin configuration.
@@ -35,6 +40,46 @@ type LinkConfig struct {
 	Replacement string `yaml:"replacement"`
 }
 
+// LaunchConfig controls how applications are started.
+type LaunchConfig struct {
+	// Terminal is the command used to run applications with Terminal=true,
+	// e.g. "alacritty -e". A "{cmd}" argument is replaced by the application's
+	// command line; otherwise the command line is appended. When empty, the
+	// terminal is detected from $TERMINAL and common emulators.
+	Terminal string `yaml:"terminal"`
+	// CommandsInTerminal runs commands from $PATH and "> command" lines in the
+	// terminal by default. Ctrl+Enter launches them the other way.
+	CommandsInTerminal bool `yaml:"commands_in_terminal"`
+}
+
+// IconConfig controls icon theme lookup.
+type IconConfig struct {
+	// Theme overrides the icon theme detected from the desktop settings.
+	Theme string `yaml:"theme"`
+}
+
+// HistoryConfig controls the launch history used to rank frequently and
+// recently launched entries first.
+type HistoryConfig struct {
+	// Disabled stops recording launches and ranking by them.
+	Disabled bool `yaml:"disabled"`
+	// Weight scales the ranking bonus, which grows with the logarithm of the
+	// decayed launch count. Zero uses the default of 60; for comparison, a
+	// name match outranks a keyword match by 250 to 350.
+	Weight float64 `yaml:"weight"`
+	// QueryWeight scales the bonus of entries previously picked for the
+	// query being typed. Zero uses the default of 250, enough for a few
+	// picks to outrank a better match.
+	QueryWeight float64 `yaml:"query_weight"`
+	// HalfLife is the time after which a launch counts half as much, such as
+	// "72h". Zero uses a week.
+	HalfLife time.Duration `yaml:"half_life"`
+}
+
+// ErrNotFound is returned by Load when no config file exists. Running without
+// a config file is supported, so callers usually ignore it.
+var ErrNotFound = errors.New("config file not found")
+
 var (
 	loadOnce sync.Once
 	loaded   Config
@@ -73,6 +118,19 @@ func Path() string {
 	return pathUsed
 }
 
+// StateDir returns $XDG_STATE_HOME/launcher, falling back to
+// ~/.local/state/launcher, where logs and history are kept.
+func StateDir() (string, error) {
+	if stateHome := os.Getenv("XDG_STATE_HOME"); stateHome != "" {
+		return filepath.Join(stateHome, "launcher"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("locate state directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "state", "launcher"), nil
+}
+
 func findConfigPath() (string, error) {
 	paths := candidatePaths()
 	for _, candidate := range paths {
@@ -88,7 +146,7 @@ func findConfigPath() (string, error) {
 		}
 		return candidate, nil
 	}
-	return "", fmt.Errorf("config file not found in any of: %s", strings.Join(paths, ", "))
+	return "", fmt.Errorf("%w in any of: %s", ErrNotFound, strings.Join(paths, ", "))
 }
 
 func candidatePaths() []string {
@@ -0,0 +1,183 @@
+// Package history records launches and ranks entries by frecency: how often
+// they were launched, with older launches counting for less.
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/SagenKoder/launcher/internal/config"
+)
+
+const (
+	fileName = "history.json"
+	// DefaultHalfLife is how long it takes for a launch to count half as much
+	// when the configuration does not say otherwise.
+	DefaultHalfLife = 7 * 24 * time.Hour
+	// maxEntries bounds the history; the entries with the lowest frecency are
+	// dropped first.
+	maxEntries = 1000
+)
+
+// Entry is the launch history of one application, action or plugin.
+type Entry struct {
+	// Score is the number of launches, each decayed to Last.
+	Score float64   `json:"score"`
+	Count int       `json:"count"`
+	Last  time.Time `json:"last"`
+}
+
+// Store is the launch history kept in $XDG_STATE_HOME/launcher. It is safe
+// for concurrent use.
+type Store struct {
+	mu       sync.Mutex
+	path     string
+	halfLife time.Duration
+	entries  map[string]Entry
+}
+
+// Open reads the history file. A missing file yields an empty store; a damaged
+// one is reported but also yields an empty store, which replaces it on the
+// next Save.
+func Open(halfLife time.Duration) (*Store, error) {
+	if halfLife <= 0 {
+		halfLife = DefaultHalfLife
+	}
+	s := &Store{halfLife: halfLife, entries: make(map[string]Entry)}
+	dir, err := config.StateDir()
+	if err != nil {
+		return s, err
+	}
+	s.path = filepath.Join(dir, fileName)
+	err = readJSON(s.path, &s.entries)
+	if err != nil || s.entries == nil {
+		s.entries = make(map[string]Entry)
+	}
+	return s, err
+}
+
+// Record adds a launch of key at now.
+func (s *Store) Record(key string, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := s.entries[key]
+	entry.Score = decay(entry, now, s.halfLife) + 1
+	entry.Count++
+	entry.Last = now
+	s.entries[key] = entry
+	s.prune(now)
+}
+
+// Forget removes key from the history and reports whether it was there.
+func (s *Store) Forget(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.entries[key]
+	delete(s.entries, key)
+	return ok
+}
+
+// Frecency returns the decayed launch count of key at now: a launch right now
+// counts 1 and one from a half-life ago counts 0.5.
+func (s *Store) Frecency(key string, now time.Time) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[key]
+	if !ok {
+		return 0
+	}
+	return decay(entry, now, s.halfLife)
+}
+
+// decay returns entry's score at now.
+func decay(entry Entry, now time.Time, halfLife time.Duration) float64 {
+	if entry.Score == 0 {
+		return 0
+	}
+	age := now.Sub(entry.Last)
+	if age <= 0 {
+		return entry.Score
+	}
+	return entry.Score * math.Exp2(-float64(age)/float64(halfLife))
+}
+
+// prune drops the least relevant entries beyond maxEntries. The caller holds
+// s.mu.
+func (s *Store) prune(now time.Time) {
+	if len(s.entries) <= maxEntries {
+		return
+	}
+	keys := make([]string, 0, len(s.entries))
+	for key := range s.entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return decay(s.entries[keys[i]], now, s.halfLife) > decay(s.entries[keys[j]], now, s.halfLife)
+	})
+	for _, key := range keys[maxEntries:] {
+		delete(s.entries, key)
+	}
+}
+
+// Save writes the history atomically.
+func (s *Store) Save() error {
+	s.mu.Lock()
+	data, err := json.MarshalIndent(s.entries, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return writeFile(s.path, data)
+}
+
+// readJSON decodes the file at path into v. A missing file is not an error.
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read %s: %w", filepath.Base(path), err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("parse %q: %w", path, err)
+	}
+	return nil
+}
+
+// writeFile replaces the file at path atomically.
+func writeFile(path string, data []byte) error {
+	if path == "" {
+		return errors.New("no state directory for history")
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("save %s: %w", filepath.Base(path), err)
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("save %s: %w", filepath.Base(path), err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("save %s: %w", filepath.Base(path), err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("save %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("save %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package history
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var base = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func TestDecay(t *testing.T) {
+	const halfLife = 24 * time.Hour
+	tests := []struct {
+		name  string
+		entry Entry
+		now   time.Time
+		want  float64
+	}{
+		{"now", Entry{Score: 4, Last: base}, base, 4},
+		{"one half-life", Entry{Score: 4, Last: base}, base.Add(halfLife), 2},
+		{"two half-lives", Entry{Score: 4, Last: base}, base.Add(2 * halfLife), 1},
+		{"clock went back", Entry{Score: 4, Last: base}, base.Add(-time.Hour), 4},
+		{"never launched", Entry{}, base, 0},
+	}
+	for _, tt := range tests {
+		if got := decay(tt.entry, tt.now, halfLife); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: decay = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStoreRecord(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	s, err := Open(24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Record("firefox.desktop", base)
+	s.Record("firefox.desktop", base.Add(24*time.Hour))
+	entry := s.entries["firefox.desktop"]
+	if entry.Count != 2 || entry.Score != 1.5 || !entry.Last.Equal(base.Add(24*time.Hour)) {
+		t.Errorf("entry = %+v, want two launches scoring 1.5 at the second", entry)
+	}
+	if got := s.Frecency("firefox.desktop", base.Add(48*time.Hour)); got != 0.75 {
+		t.Errorf("Frecency a day later = %v, want 0.75", got)
+	}
+	if got := s.Frecency("missing", base); got != 0 {
+		t.Errorf("Frecency of an unknown key = %v, want 0", got)
+	}
+}
+
+func TestStorePrune(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	s, err := Open(24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range maxEntries - 2 {
+		s.entries[fmt.Sprintf("filler-%d", i)] = Entry{Score: 1, Count: 1, Last: base}
+	}
+	// A week-old entry launched often outweighs a single recent launch; a
+	// fortnight-old single launch does not.
+	s.entries["frequent"] = Entry{Score: 1000, Count: 1000, Last: base.Add(-7 * 24 * time.Hour)}
+	s.entries["stale"] = Entry{Score: 1, Count: 1, Last: base.Add(-14 * 24 * time.Hour)}
+	s.Record("new", base)
+
+	if len(s.entries) != maxEntries {
+		t.Errorf("%d entries after pruning, want %d", len(s.entries), maxEntries)
+	}
+	for key, want := range map[string]bool{"frequent": true, "new": true, "stale": false} {
+		if _, ok := s.entries[key]; ok != want {
+			t.Errorf("%s kept = %v, want %v", key, ok, want)
+		}
+	}
+}
+
+func TestStoreForget(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	s, err := Open(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Record("firefox.desktop", base)
+	if !s.Forget("firefox.desktop") {
+		t.Error("Forget of a recorded key = false")
+	}
+	if s.Forget("firefox.desktop") {
+		t.Error("Forget of a forgotten key = true")
+	}
+	if got := s.Frecency("firefox.desktop", base); got != 0 {
+		t.Errorf("Frecency after Forget = %v, want 0", got)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string // empty means no file
+		wantErr bool
+		want    int
+	}{
+		{"missing", "", false, 0},
+		{"valid", `{"firefox.desktop": {"score": 2, "count": 3, "last": "2024-03-01T12:00:00Z"}}`, false, 1},
+		{"null", "null", false, 0},
+		{"damaged", `{"firefox.desktop": {"score": `, true, 0},
+		{"wrong shape", `["firefox.desktop"]`, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := t.TempDir()
+			t.Setenv("XDG_STATE_HOME", state)
+			path := filepath.Join(state, "launcher", fileName)
+			if tt.content != "" {
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			s, err := Open(0)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Open error = %v, want error %v", err, tt.wantErr)
+			}
+			if s == nil || len(s.entries) != tt.want {
+				t.Fatalf("Open = %+v, want %d entries", s, tt.want)
+			}
+			if s.halfLife != DefaultHalfLife {
+				t.Errorf("halfLife = %v, want the default", s.halfLife)
+			}
+
+			// The store stays usable and replaces a damaged file on Save.
+			s.Record("code.desktop", base)
+			if err := s.Save(); err != nil {
+				t.Fatal(err)
+			}
+			reopened, err := Open(0)
+			if err != nil {
+				t.Fatalf("reopening the saved file: %v", err)
+			}
+			if len(reopened.entries) != tt.want+1 || reopened.entries["code.desktop"].Count != 1 {
+				t.Errorf("saved entries = %+v", reopened.entries)
+			}
+		})
+	}
+}
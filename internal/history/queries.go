@@ -0,0 +1,177 @@
+package history
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/SagenKoder/launcher/internal/config"
+)
+
+const (
+	queriesFileName = "queries.json"
+	// maxQueries bounds the number of queries remembered, and
+	// maxPicksPerQuery the entries remembered for each.
+	maxQueries       = 500
+	maxPicksPerQuery = 5
+	// relatedQueryWeight discounts picks made for the query being typed
+	// with one letter more or less.
+	relatedQueryWeight = 0.5
+)
+
+// Queries remembers which entry was picked for which query, so typing a query
+// again ranks the entry picked for it first, like learned abbreviations. It is
+// safe for concurrent use.
+type Queries struct {
+	mu       sync.Mutex
+	path     string
+	halfLife time.Duration
+	entries  map[string]map[string]Entry
+}
+
+// OpenQueries reads the query file, with the same handling of missing and
+// damaged files as Open.
+func OpenQueries(halfLife time.Duration) (*Queries, error) {
+	if halfLife <= 0 {
+		halfLife = DefaultHalfLife
+	}
+	q := &Queries{halfLife: halfLife, entries: make(map[string]map[string]Entry)}
+	dir, err := config.StateDir()
+	if err != nil {
+		return q, err
+	}
+	q.path = filepath.Join(dir, queriesFileName)
+	err = readJSON(q.path, &q.entries)
+	if err != nil || q.entries == nil {
+		q.entries = make(map[string]map[string]Entry)
+	}
+	return q, err
+}
+
+// NormalizeQuery returns the form queries are remembered in.
+func NormalizeQuery(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
+
+// Record adds a pick of key for query at now.
+func (q *Queries) Record(query, key string, now time.Time) {
+	query = NormalizeQuery(query)
+	if query == "" {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	picks := q.entries[query]
+	if picks == nil {
+		picks = make(map[string]Entry)
+		q.entries[query] = picks
+	}
+	entry := picks[key]
+	entry.Score = decay(entry, now, q.halfLife) + 1
+	entry.Count++
+	entry.Last = now
+	picks[key] = entry
+	q.prune(query, now)
+}
+
+// Forget removes every pick of key.
+func (q *Queries) Forget(key string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	found := false
+	for query, picks := range q.entries {
+		if _, ok := picks[key]; ok {
+			found = true
+			delete(picks, key)
+			if len(picks) == 0 {
+				delete(q.entries, query)
+			}
+		}
+	}
+	return found
+}
+
+// Scores returns the decayed pick counts of the entries picked for query.
+// Picks made for the query with one letter more or less count for less, so
+// typing or correcting a letter keeps most of what was learned.
+func (q *Queries) Scores(query string, now time.Time) map[string]float64 {
+	query = NormalizeQuery(query)
+	if query == "" {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var scores map[string]float64
+	for stored, picks := range q.entries {
+		weight := 1.0
+		if stored != query {
+			if !relatedQueries(stored, query) {
+				continue
+			}
+			weight = relatedQueryWeight
+		}
+		if scores == nil {
+			scores = make(map[string]float64)
+		}
+		for key, entry := range picks {
+			scores[key] += weight * decay(entry, now, q.halfLife)
+		}
+	}
+	return scores
+}
+
+// relatedQueries reports whether one query extends the other by one rune.
+func relatedQueries(a, b string) bool {
+	if len(a) < len(b) {
+		a, b = b, a
+	}
+	rest, ok := strings.CutPrefix(a, b)
+	return ok && utf8.RuneCountInString(rest) == 1
+}
+
+// prune keeps the strongest picks for query and drops the weakest queries
+// beyond maxQueries. The caller holds q.mu.
+func (q *Queries) prune(query string, now time.Time) {
+	if picks := q.entries[query]; len(picks) > maxPicksPerQuery {
+		keys := make([]string, 0, len(picks))
+		for key := range picks {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return decay(picks[keys[i]], now, q.halfLife) > decay(picks[keys[j]], now, q.halfLife)
+		})
+		for _, key := range keys[maxPicksPerQuery:] {
+			delete(picks, key)
+		}
+	}
+	if len(q.entries) <= maxQueries {
+		return
+	}
+	strength := make(map[string]float64, len(q.entries))
+	queries := make([]string, 0, len(q.entries))
+	for stored, picks := range q.entries {
+		for _, entry := range picks {
+			strength[stored] = max(strength[stored], decay(entry, now, q.halfLife))
+		}
+		queries = append(queries, stored)
+	}
+	sort.Slice(queries, func(i, j int) bool { return strength[queries[i]] > strength[queries[j]] })
+	for _, stored := range queries[maxQueries:] {
+		delete(q.entries, stored)
+	}
+}
+
+// Save writes the queries atomically.
+func (q *Queries) Save() error {
+	q.mu.Lock()
+	data, err := json.MarshalIndent(q.entries, "", "  ")
+	q.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return writeFile(q.path, data)
+}
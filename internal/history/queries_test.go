@@ -0,0 +1,128 @@
+package history
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNormalizeQuery(t *testing.T) {
+	for query, want := range map[string]string{
+		"":              "",
+		"  Fire  FOX  ": "fire fox",
+		"code":          "code",
+	} {
+		if got := NormalizeQuery(query); got != want {
+			t.Errorf("NormalizeQuery(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
+
+func TestQueriesScores(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	q, err := OpenQueries(24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pick := range []struct{ query, key string }{
+		{"fi", "files"},
+		{"fir", "firefox"},
+		{"Fir", "firefox"},
+		{"fire", "firefox"},
+		{"firef", "firefox-beta"},
+		{"caf", "cafe"},
+		{"café", "cafe-fr"},
+		{"  ", "ignored"},
+	} {
+		q.Record(pick.query, pick.key, base)
+	}
+
+	tests := []struct {
+		query string
+		at    time.Time
+		want  map[string]float64
+	}{
+		// Exact picks count fully, one letter more or less counts half and
+		// two letters or more nothing.
+		{"fir", base, map[string]float64{"firefox": 2.5, "files": 0.5}},
+		{"FIRE ", base, map[string]float64{"firefox": 2, "firefox-beta": 0.5}},
+		{"f", base, map[string]float64{"files": 0.5}},
+		{"ca", base, map[string]float64{"cafe": 0.5}},
+		{"caf", base, map[string]float64{"cafe": 1, "cafe-fr": 0.5}},
+		{"fir", base.Add(24 * time.Hour), map[string]float64{"firefox": 1.25, "files": 0.25}},
+		{"zzz", base, nil},
+		{"", base, nil},
+	}
+	for _, tt := range tests {
+		if got := q.Scores(tt.query, tt.at); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Scores(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestQueriesPruneCaps(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	q, err := OpenQueries(24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The weakest pick beyond maxPicksPerQuery goes: every key but
+	// "weakest" is picked again later.
+	q.Record("term", "weakest", base)
+	for i := range maxPicksPerQuery {
+		q.Record("term", fmt.Sprintf("key-%d", i), base.Add(time.Hour))
+	}
+	picks := q.entries["term"]
+	if _, ok := picks["weakest"]; ok || len(picks) != maxPicksPerQuery {
+		t.Errorf("picks for term = %v, want %d without weakest", picks, maxPicksPerQuery)
+	}
+
+	// The oldest queries beyond maxQueries go.
+	for i := range maxQueries {
+		q.Record(fmt.Sprintf("query %d", i), "app", base.Add(time.Duration(i+2)*time.Hour))
+	}
+	if len(q.entries) != maxQueries {
+		t.Errorf("%d queries, want %d", len(q.entries), maxQueries)
+	}
+	if _, ok := q.entries["term"]; ok {
+		t.Error("the oldest query was kept")
+	}
+	if _, ok := q.entries[fmt.Sprintf("query %d", maxQueries-1)]; !ok {
+		t.Error("the newest query was dropped")
+	}
+}
+
+func TestQueriesForget(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	q, err := OpenQueries(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.Record("fir", "firefox", base)
+	q.Record("fir", "files", base)
+	q.Record("ff", "firefox", base)
+
+	if !q.Forget("firefox") {
+		t.Error("Forget of a picked key = false")
+	}
+	if q.Forget("firefox") {
+		t.Error("Forget of a forgotten key = true")
+	}
+	want := map[string]map[string]Entry{"fir": {"files": {Score: 1, Count: 1, Last: base}}}
+	if !reflect.DeepEqual(q.entries, want) {
+		t.Errorf("entries after Forget = %v, want %v", q.entries, want)
+	}
+
+	if err := q.Save(); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := OpenQueries(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reopened.Scores("fir", base); !reflect.DeepEqual(got, map[string]float64{"files": 1}) {
+		t.Errorf("Scores after reopening = %v", got)
+	}
+}
@@ -54,6 +54,11 @@ func (b *pluginBadge) Set(resource fyne.Resource, text string) {
 	}
 }
 
+// SetIcon replaces the icon once it has finished loading.
+func (b *pluginBadge) SetIcon(resource fyne.Resource) {
+	b.icon.SetResource(resource)
+}
+
 func (b *pluginBadge) Show() {
 	b.container.Show()
 	b.container.Refresh()
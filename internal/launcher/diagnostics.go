@@ -0,0 +1,156 @@
+package launcher
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+
+	"github.com/SagenKoder/launcher/internal/plugins"
+)
+
+const diagnosticsPluginID = "diagnostics"
+
+type severity int
+
+const (
+	severityWarning severity = iota
+	severityError
+)
+
+// diagnostic is a problem worth showing to the user. Errors are also raised
+// as notices in the window; warnings are only listed under Diagnostics.
+type diagnostic struct {
+	time     time.Time
+	severity severity
+	summary  string
+	detail   string
+}
+
+// diagnosticsLog collects problems from anywhere in the launcher. It is safe
+// for concurrent use; notices are delivered on the UI goroutine.
+type diagnosticsLog struct {
+	mu       sync.Mutex
+	entries  []diagnostic
+	onNotice func(diagnostic)
+}
+
+// maxDiagnostics bounds the log so a persistently failing watcher cannot grow
+// it without limit.
+const maxDiagnostics = 500
+
+var diagnostics = &diagnosticsLog{}
+
+// Error records a problem the user should see right away.
+func (d *diagnosticsLog) Error(summary string, err error) {
+	d.add(severityError, summary, err)
+}
+
+// Warn records a problem that is only listed under Diagnostics. Joined errors
+// are recorded one by one, and a warning already in the log is not recorded
+// again.
+func (d *diagnosticsLog) Warn(summary string, err error) {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, err := range joined.Unwrap() {
+			d.Warn(summary, err)
+		}
+		return
+	}
+	d.add(severityWarning, summary, err)
+}
+
+func (d *diagnosticsLog) add(sev severity, summary string, err error) {
+	entry := diagnostic{time: time.Now(), severity: sev, summary: summary}
+	if err != nil {
+		entry.detail = err.Error()
+	}
+
+	d.mu.Lock()
+	if sev == severityWarning && d.seen(entry) {
+		d.mu.Unlock()
+		return
+	}
+	if err != nil {
+		log.Printf("%s: %v", summary, err)
+	} else {
+		log.Print(summary)
+	}
+	if len(d.entries) >= maxDiagnostics {
+		d.entries = append(d.entries[:0], d.entries[1:]...)
+	}
+	d.entries = append(d.entries, entry)
+	onNotice := d.onNotice
+	d.mu.Unlock()
+
+	if sev == severityError && onNotice != nil {
+		fyne.CurrentApp().Driver().DoFromGoroutine(func() { onNotice(entry) }, false)
+	}
+}
+
+// seen reports whether a problem with the same summary and detail is already
+// in the log. The caller holds d.mu.
+func (d *diagnosticsLog) seen(entry diagnostic) bool {
+	for _, existing := range d.entries {
+		if existing.summary == entry.summary && existing.detail == entry.detail {
+			return true
+		}
+	}
+	return false
+}
+
+// SetOnNotice routes errors to fn, including the ones recorded before the
+// window existed.
+func (d *diagnosticsLog) SetOnNotice(fn func(diagnostic)) {
+	d.mu.Lock()
+	d.onNotice = fn
+	var pending []diagnostic
+	for _, entry := range d.entries {
+		if entry.severity == severityError {
+			pending = append(pending, entry)
+		}
+	}
+	d.mu.Unlock()
+	for _, entry := range pending {
+		fn(entry)
+	}
+}
+
+// Markdown renders every recorded problem, newest first.
+func (d *diagnosticsLog) Markdown() string {
+	d.mu.Lock()
+	entries := append([]diagnostic(nil), d.entries...)
+	d.mu.Unlock()
+	if len(entries) == 0 {
+		return "No problems have been recorded."
+	}
+
+	var b strings.Builder
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		label := "Warning"
+		if entry.severity == severityError {
+			label = "Error"
+		}
+		fmt.Fprintf(&b, "**%s** %s — %s\n\n", label, entry.time.Format("15:04:05"), entry.summary)
+		if entry.detail != "" {
+			fmt.Fprintf(&b, "```\n%s\n```\n\n", entry.detail)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// diagnosticsPlugin lists the recorded problems, including the warnings found
+// while discovering applications.
+func diagnosticsPlugin() plugins.Info {
+	return plugins.Info{
+		ID:    diagnosticsPluginID,
+		Name:  "Diagnostics",
+		Intro: "Problems found while discovering and launching applications.",
+		OnInit: func() (string, error) {
+			return diagnostics.Markdown(), nil
+		},
+	}
+}
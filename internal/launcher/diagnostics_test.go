@@ -0,0 +1,25 @@
+package launcher
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDiagnosticsWarnDedupes(t *testing.T) {
+	d := &diagnosticsLog{}
+	parse := errors.New("parse broken.desktop: unterminated quote")
+	for i := 0; i < 3; i++ {
+		d.Warn("Problem discovering applications", errors.Join(parse, errors.New("list flatpak: exit status 1")))
+	}
+	d.Warn("Problem discovering applications", errors.New("list snap: exit status 1"))
+	d.Warn("Could not watch application directories", parse)
+	if got := len(d.entries); got != 4 {
+		t.Errorf("recorded %d warnings, want 4", got)
+	}
+
+	d.Error("Could not launch Editor", parse)
+	d.Error("Could not launch Editor", parse)
+	if got := len(d.entries); got != 6 {
+		t.Errorf("recorded %d entries, want errors kept each time", got)
+	}
+}
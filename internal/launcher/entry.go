@@ -2,14 +2,17 @@ package launcher
 
 import (
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
 	"fyne.io/fyne/v2/widget"
 )
 
 type launcherEntry struct {
 	widget.Entry
-	onEscape        func()
-	onMoveSelection func(delta int)
-	onActivate      func()
+	onEscape      func()
+	onNavigate    func(key fyne.KeyName) bool
+	onActivate    func()
+	onAltActivate func()
+	onForget      func() bool
 }
 
 func newLauncherEntry(onEscape func()) *launcherEntry {
@@ -24,15 +27,15 @@ func (e *launcherEntry) TypedKey(event *fyne.KeyEvent) {
 		if e.onEscape != nil {
 			e.onEscape()
 		}
-	case fyne.KeyDown:
-		if e.onMoveSelection != nil {
-			e.onMoveSelection(1)
+	case fyne.KeyDown, fyne.KeyUp, fyne.KeyPageDown, fyne.KeyPageUp:
+		if e.onNavigate != nil && e.onNavigate(event.Name) {
 			return
 		}
 		e.Entry.TypedKey(event)
-	case fyne.KeyUp:
-		if e.onMoveSelection != nil {
-			e.onMoveSelection(-1)
+	case fyne.KeyDelete:
+		// Shift+Delete forgets the selected entry's launch history, as it
+		// removes suggestions in browsers.
+		if e.onForget != nil && shiftPressed() && e.onForget() {
 			return
 		}
 		e.Entry.TypedKey(event)
@@ -47,10 +50,47 @@ func (e *launcherEntry) TypedKey(event *fyne.KeyEvent) {
 	}
 }
 
-func (e *launcherEntry) SetOnMoveSelection(fn func(int)) {
-	e.onMoveSelection = fn
+// TypedShortcut runs the alternate activation on Ctrl+Enter and jumps to the
+// first or last result on Ctrl+Home and Ctrl+End; plain Home and End still
+// move the cursor.
+func (e *launcherEntry) TypedShortcut(shortcut fyne.Shortcut) {
+	if custom, ok := shortcut.(*desktop.CustomShortcut); ok && custom.Modifier == fyne.KeyModifierShortcutDefault {
+		switch custom.KeyName {
+		case fyne.KeyReturn, fyne.KeyEnter:
+			if e.onAltActivate != nil {
+				e.onAltActivate()
+				return
+			}
+		case fyne.KeyHome, fyne.KeyEnd:
+			if e.onNavigate != nil && e.onNavigate(custom.KeyName) {
+				return
+			}
+		}
+	}
+	e.Entry.TypedShortcut(shortcut)
+}
+
+// SetOnNavigate routes the keys that move the result selection to fn, which
+// reports whether it handled the key.
+func (e *launcherEntry) SetOnNavigate(fn func(fyne.KeyName) bool) {
+	e.onNavigate = fn
 }
 
 func (e *launcherEntry) SetOnActivate(fn func()) {
 	e.onActivate = fn
 }
+
+func (e *launcherEntry) SetOnAltActivate(fn func()) {
+	e.onAltActivate = fn
+}
+
+// SetOnForget sets the handler for Shift+Delete, which reports whether it
+// forgot anything.
+func (e *launcherEntry) SetOnForget(fn func() bool) {
+	e.onForget = fn
+}
+
+func shiftPressed() bool {
+	drv, ok := fyne.CurrentApp().Driver().(desktop.Driver)
+	return ok && drv.CurrentKeyModifiers()&fyne.KeyModifierShift != 0
+}
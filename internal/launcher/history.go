@@ -0,0 +1,112 @@
+package launcher
+
+import (
+	"math"
+	"time"
+
+	"github.com/SagenKoder/launcher/internal/config"
+	"github.com/SagenKoder/launcher/internal/history"
+	"github.com/SagenKoder/launcher/internal/search"
+)
+
+const (
+	// defaultHistoryWeight makes an application launched every day for a
+	// couple of weeks outrank one matching slightly better but never
+	// launched.
+	defaultHistoryWeight = 60
+	// defaultQueryWeight makes an entry picked three times for a query
+	// outrank a name match for it.
+	defaultQueryWeight = 250
+)
+
+// launchHistory and queryHistory are nil when history is disabled.
+var (
+	launchHistory *history.Store
+	queryHistory  *history.Queries
+)
+
+// openHistory loads the launch and query history and ranks search results by
+// them.
+func openHistory(cfg config.HistoryConfig) {
+	if cfg.Disabled {
+		return
+	}
+	store, err := history.Open(cfg.HalfLife)
+	if err != nil {
+		diagnostics.Warn("Could not read launch history", err)
+	}
+	queries, err := history.OpenQueries(cfg.HalfLife)
+	if err != nil {
+		diagnostics.Warn("Could not read query history", err)
+	}
+	launchHistory, queryHistory = store, queries
+
+	weight := cfg.Weight
+	if weight == 0 {
+		weight = defaultHistoryWeight
+	}
+	queryWeight := cfg.QueryWeight
+	if queryWeight == 0 {
+		queryWeight = defaultQueryWeight
+	}
+	search.SetBoost(func(item search.Item, query string) int {
+		if !picks.valid || picks.query != query {
+			picks.query, picks.now, picks.valid = query, time.Now(), true
+			picks.scores = queries.Scores(query, picks.now)
+		}
+		return int(weight*math.Log1p(store.Frecency(item.ID, picks.now)) +
+			queryWeight*math.Log1p(picks.scores[item.ID]))
+	})
+}
+
+// picks caches the entries picked for the query being ranked, so they are
+// looked up once per query rather than once per application. Like searching,
+// it is only used on the UI goroutine.
+var picks struct {
+	valid  bool
+	query  string
+	now    time.Time
+	scores map[string]float64
+}
+
+// learnQuery remembers that the item with the given ID was picked for query.
+func learnQuery(query, id string) {
+	if queryHistory == nil || history.NormalizeQuery(query) == "" {
+		return
+	}
+	queryHistory.Record(query, id, time.Now())
+	picks.valid = false
+	if err := queryHistory.Save(); err != nil {
+		diagnostics.Warn("Could not save query history", err)
+	}
+}
+
+// recordLaunch adds a launch of the item with the given ID to the history.
+func recordLaunch(id string) {
+	if launchHistory == nil {
+		return
+	}
+	launchHistory.Record(id, time.Now())
+	if err := launchHistory.Save(); err != nil {
+		diagnostics.Warn("Could not save launch history", err)
+	}
+}
+
+// forgetLaunches removes the item with the given ID from the launch and query
+// history so it no longer ranks higher.
+func forgetLaunches(id string) {
+	if launchHistory == nil {
+		return
+	}
+	picks.valid = false
+	if launchHistory.Forget(id) {
+		if err := launchHistory.Save(); err != nil {
+			diagnostics.Warn("Could not save launch history", err)
+		}
+	}
+	if queryHistory.Forget(id) {
+		if err := queryHistory.Save(); err != nil {
+			diagnostics.Warn("Could not save query history", err)
+		}
+	}
+}
@@ -1,43 +1,157 @@
 package launcher
 
 import (
-	"log"
+	"container/list"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/theme"
+
+	"github.com/SagenKoder/launcher/internal/applications"
 )
 
-var (
-	iconCache   = make(map[string]fyne.Resource)
-	iconCacheMu sync.Mutex
+const (
+	// iconCacheBytes bounds the memory held by loaded icons. Icons are
+	// rendered at ListIconSize, so this keeps a few thousand of them.
+	iconCacheBytes = 8 << 20
+	// iconWorkers bounds the number of icons read and decoded at once.
+	iconWorkers = 4
 )
 
-func iconResource(path string) fyne.Resource {
+// iconLoader reads and rasterizes icons off the UI goroutine. Loaded icons are
+// kept in a least-recently-used cache bounded by their size in bytes.
+type iconLoader struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	recent  *list.List // of iconEntry, most recently used first
+	size    int
+	failed  map[string]struct{}
+	pending map[string][]func(fyne.Resource)
+	// queue holds paths waiting for a worker. It is served newest first so
+	// the rows of the latest query are loaded before older ones.
+	queue   []string
+	running int
+}
+
+type iconEntry struct {
+	path string
+	res  fyne.Resource
+}
+
+var icons = &iconLoader{
+	entries: make(map[string]*list.Element),
+	recent:  list.New(),
+	failed:  make(map[string]struct{}),
+	pending: make(map[string][]func(fyne.Resource)),
+}
+
+// Load returns the icon at path, or the theme icon of that name, if it is
+// already loaded. Otherwise it returns a placeholder and calls ready on the UI
+// goroutine once the icon is loaded. Icons that fail to load keep the
+// placeholder.
+func (l *iconLoader) Load(path string, ready func(fyne.Resource)) fyne.Resource {
 	if path == "" {
 		return theme.FileApplicationIcon()
 	}
 
-	iconCacheMu.Lock()
-	defer iconCacheMu.Unlock()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if elem, ok := l.entries[path]; ok {
+		l.recent.MoveToFront(elem)
+		return elem.Value.(iconEntry).res
+	}
+	if _, ok := l.failed[path]; ok {
+		return theme.FileApplicationIcon()
+	}
+	if waiting, ok := l.pending[path]; ok {
+		l.pending[path] = append(waiting, ready)
+		return theme.FileApplicationIcon()
+	}
+	l.pending[path] = []func(fyne.Resource){ready}
+	l.queue = append(l.queue, path)
+	if l.running < iconWorkers {
+		l.running++
+		go l.work()
+	}
+	return theme.FileApplicationIcon()
+}
 
-	if res, ok := iconCache[path]; ok {
-		if res == nil {
-			return theme.FileApplicationIcon()
+func (l *iconLoader) work() {
+	for {
+		l.mu.Lock()
+		if len(l.queue) == 0 {
+			l.running--
+			l.mu.Unlock()
+			return
 		}
-		return res
+		path := l.queue[len(l.queue)-1]
+		l.queue = l.queue[:len(l.queue)-1]
+		l.mu.Unlock()
+
+		res, err := loadIcon(path)
+
+		l.mu.Lock()
+		waiting := l.pending[path]
+		delete(l.pending, path)
+		if err != nil {
+			l.failed[path] = struct{}{}
+		} else {
+			l.store(path, res)
+		}
+		l.mu.Unlock()
+
+		if err != nil {
+			// Themes routinely ship icons that fail to decode, so failures
+			// are only listed under Diagnostics.
+			if !errors.Is(err, errIconNotFound) {
+				diagnostics.Warn(fmt.Sprintf("Could not load icon %s", filepath.Base(path)), err)
+			}
+			continue
+		}
+		fyne.CurrentApp().Driver().DoFromGoroutine(func() {
+			for _, ready := range waiting {
+				if ready != nil {
+					ready(res)
+				}
+			}
+		}, false)
 	}
+}
 
-	data, err := os.ReadFile(path)
-	if err != nil {
-		log.Printf("failed to read icon %s: %v", path, err)
-		iconCache[path] = nil
-		return theme.FileApplicationIcon()
+// store adds res to the cache and evicts the least recently used icons that
+// no longer fit. The caller holds l.mu.
+func (l *iconLoader) store(path string, res fyne.Resource) {
+	l.entries[path] = l.recent.PushFront(iconEntry{path: path, res: res})
+	l.size += len(res.Content())
+	for l.size > iconCacheBytes && l.recent.Len() > 1 {
+		evicted := l.recent.Remove(l.recent.Back()).(iconEntry)
+		delete(l.entries, evicted.path)
+		l.size -= len(evicted.res.Content())
 	}
+}
+
+// errIconNotFound marks theme icons missing from the installed themes, which
+// is common enough not to be worth reporting.
+var errIconNotFound = errors.New("icon not found")
 
-	res := fyne.NewStaticResource(filepath.Base(path), data)
-	iconCache[path] = res
-	return res
+// loadIcon reads the icon at path, rasterized or scaled to ListIconSize so the
+// UI never decodes full-size images. Names of theme icons, as plugins use,
+// are resolved here rather than on the UI goroutine.
+func loadIcon(path string) (fyne.Resource, error) {
+	if path = applications.ResolveIcon(path); path == "" {
+		return nil, errIconNotFound
+	}
+	renderable, err := applications.RenderableIcon(path, applications.ListIconSize)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(renderable)
+	if err != nil {
+		return nil, err
+	}
+	return fyne.NewStaticResource(filepath.Base(renderable), data), nil
 }
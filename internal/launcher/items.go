@@ -0,0 +1,119 @@
+package launcher
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/SagenKoder/launcher/internal/applications"
+	"github.com/SagenKoder/launcher/internal/plugins"
+	"github.com/SagenKoder/launcher/internal/search"
+)
+
+// buildCatalog combines discovered applications with their desktop actions and
+// the registered plugins into the items searched by the entry.
+func buildCatalog(discovered []applications.Application) []search.Item {
+	items := make([]search.Item, 0, len(discovered))
+	for _, app := range discovered {
+		if app.NoDisplay {
+			continue
+		}
+		item := applicationItem(app)
+		for _, action := range applications.ExpandActions([]applications.Application{app}) {
+			actionItem := applicationItem(action)
+			item.Actions = append(item.Actions, actionItem)
+			items = append(items, actionItem)
+		}
+		items = append(items, item)
+	}
+	for _, info := range plugins.All() {
+		items = append(items, pluginItem(info))
+	}
+	sort.Slice(items, func(i, j int) bool {
+		titleI := strings.ToLower(items[i].Title)
+		titleJ := strings.ToLower(items[j].Title)
+		if titleI == titleJ {
+			return items[i].Command < items[j].Command
+		}
+		return titleI < titleJ
+	})
+	return items
+}
+
+// applicationItems wraps apps, keeping their order.
+func applicationItems(apps []applications.Application) []search.Item {
+	items := make([]search.Item, len(apps))
+	for i, app := range apps {
+		items[i] = applicationItem(app)
+	}
+	return items
+}
+
+func applicationItem(app applications.Application) search.Item {
+	kind := search.KindApplication
+	switch {
+	case app.ActionID != "":
+		kind = search.KindAction
+	case app.Source == applications.SourcePath:
+		kind = search.KindCommand
+	}
+	return search.Item{
+		ID:          applicationID(app),
+		Kind:        kind,
+		Title:       app.Name,
+		Subtitle:    app.GenericName,
+		Detail:      detailLabel(app),
+		Source:      app.Source,
+		Keywords:    app.Keywords,
+		IconPath:    app.IconPath,
+		Aliases:     []string{app.UntranslatedName},
+		Categories:  app.Categories,
+		Description: app.Comment,
+		Command:     app.Exec,
+		Value:       app,
+	}
+}
+
+// applicationID identifies app in the launch history. Desktop actions are
+// kept apart from their application.
+func applicationID(app applications.Application) string {
+	id := app.ID
+	if id == "" {
+		id = app.Exec
+	}
+	if app.ActionID != "" {
+		id += "#" + app.ActionID
+	}
+	return id
+}
+
+func pluginItem(info plugins.Info) search.Item {
+	return search.Item{
+		// Plugin IDs are namespaced by kind so they cannot collide with
+		// desktop file IDs in the launch history.
+		ID:       string(search.KindPlugin) + ":" + info.ID,
+		Kind:     search.KindPlugin,
+		Title:    info.Name,
+		Source:   "plugin",
+		IconPath: info.Icon,
+		Value:    info,
+	}
+}
+
+// detailLabel names the packaging format of applications that did not come
+// from a plain desktop entry, or notes that a macOS app lives in the menu bar.
+func detailLabel(app applications.Application) string {
+	if app.Agent {
+		return "Menu bar"
+	}
+	switch app.Source {
+	case applications.SourceFlatpak:
+		return "Flatpak"
+	case applications.SourceSnap:
+		return "Snap"
+	case applications.SourceAppImage:
+		return "AppImage"
+	case applications.SourcePath:
+		return "Command"
+	}
+	return ""
+}
@@ -2,11 +2,8 @@ package launcher
 
 import (
 	"fmt"
-	"log"
 	"os"
-	"os/exec"
 	"runtime"
-	"sort"
 	"strings"
 
 	"fyne.io/fyne/v2"
@@ -15,11 +12,21 @@ import (
 	fynedesktop "fyne.io/fyne/v2/driver/desktop"
 
 	"github.com/SagenKoder/launcher/internal/applications"
+	"github.com/SagenKoder/launcher/internal/config"
 	"github.com/SagenKoder/launcher/internal/plugins"
+	"github.com/SagenKoder/launcher/internal/process"
 	"github.com/SagenKoder/launcher/internal/search"
 )
 
 func Run() {
+	plugins.Register(diagnosticsPlugin())
+	for _, err := range plugins.Errors() {
+		diagnostics.Error("Plugin configuration problem", err)
+	}
+
+	cfg, _ := config.Load()
+	openHistory(cfg.History)
+
 	application := app.New()
 	window := application.NewWindow("Launcher")
 
@@ -27,22 +34,15 @@ func Run() {
 	window.CenterOnScreen()
 	window.SetFixedSize(false)
 
-	apps, err := applications.List()
-	if err != nil {
-		log.Printf("failed to load applications: %v", err)
-	}
-
-	apps = append(apps, pluginApplications()...)
-	sort.Slice(apps, func(i, j int) bool {
-		nameI := strings.ToLower(apps[i].Name)
-		nameJ := strings.ToLower(apps[j].Name)
-		if nameI == nameJ {
-			return apps[i].Exec < apps[j].Exec
-		}
-		return nameI < nameJ
-	})
+	// The window opens with the plugins and the cached applications, if any.
+	// The watcher below merges in the results of discovery as they arrive.
+	discovered, _ := applications.CachedList()
+	catalog := buildCatalog(discovered)
 
-	filtered := make([]applications.Application, 0)
+	var filtered []search.Result
+	// openTarget is the file or URL typed into the entry while the list shows
+	// the applications that can open it.
+	var openTarget string
 	list := newLauncherList(window.Close)
 	pluginDisplay := newPluginDisplay(window)
 	badge := newPluginBadge()
@@ -65,7 +65,7 @@ func Run() {
 	showPlugin := func(id string) {
 		info, ok := registry[id]
 		if !ok {
-			log.Printf("unknown plugin id %q", id)
+			diagnostics.Error(fmt.Sprintf("Unknown plugin %q", id), nil)
 			return
 		}
 		infoCopy := info
@@ -74,7 +74,11 @@ func Run() {
 		body.Objects = []fyne.CanvasObject{pluginDisplay.Container()}
 		body.Refresh()
 		badge.Show()
-		badge.Set(iconResource(infoCopy.IconPath), infoCopy.Name)
+		badge.Set(icons.Load(infoCopy.Icon, func(res fyne.Resource) {
+			if activePlugin != nil && activePlugin.ID == infoCopy.ID {
+				badge.SetIcon(res)
+			}
+		}), infoCopy.Name)
 		if entry != nil {
 			if infoCopy.Hint != "" {
 				entry.SetPlaceHolder(infoCopy.Hint)
@@ -105,9 +109,23 @@ func Run() {
 		window.Close()
 	})
 	entry.SetPlaceHolder(defaultPlaceholder)
-	entry.SetOnMoveSelection(func(delta int) {
-		list.MoveSelection(delta)
+	entry.SetOnNavigate(func(key fyne.KeyName) bool {
+		return activePlugin == nil && list.Navigate(key)
 	})
+	// activate launches an item picked from the list. Picks from a search
+	// are remembered for the query.
+	activate := func(item search.Item, alternate bool) {
+		if openTarget == "" && !strings.HasPrefix(strings.TrimSpace(entry.Text), ">") {
+			learnQuery(entry.Text, item.ID)
+		}
+		switch value := item.Value.(type) {
+		case plugins.Info:
+			recordLaunch(item.ID)
+			showPlugin(value.ID)
+		case applications.Application:
+			launchApplication(window, value, alternate, targets(openTarget)...)
+		}
+	}
 	runSelected := func() {
 		if activePlugin != nil {
 			text := entry.Text
@@ -126,15 +144,23 @@ func Run() {
 			clearEntry()
 			return
 		}
-		if app, ok := list.SelectedApplication(); ok {
-			launchApplication(window, app, showPlugin)
+		if item, ok := list.SelectedItem(); ok {
+			activate(item, false)
 		}
 	}
 	entry.SetOnActivate(runSelected)
-	list.SetOnActivate(func(app applications.Application) {
-		launchApplication(window, app, showPlugin)
+	entry.SetOnAltActivate(func() {
+		if activePlugin != nil {
+			runSelected()
+			return
+		}
+		if item, ok := list.SelectedItem(); ok {
+			activate(item, true)
+		}
+	})
+	list.SetOnActivate(func(item search.Item) {
+		activate(item, false)
 	})
-
 	updateFilter := func(text string) {
 		if activePlugin != nil {
 			if activePlugin.OnChange != nil {
@@ -142,20 +168,52 @@ func Run() {
 			}
 			return
 		}
-		filtered = search.Filter(apps, text)
-		list.SetApplications(filtered)
+		openTarget = ""
+		if line, ok := strings.CutPrefix(strings.TrimSpace(text), ">"); ok {
+			// "> command args" runs an arbitrary command line.
+			filtered = nil
+			if line = strings.TrimSpace(line); line != "" {
+				filtered = []search.Result{{Item: applicationItem(applications.CommandLine(line))}}
+			}
+		} else if target, ok := applications.OpenTarget(text); ok {
+			// A file path or URL lists the applications that can open it.
+			openTarget = target
+			filtered = filtered[:0]
+			for _, item := range applicationItems(applications.Handlers(discovered, applications.MimeTypeOf(target))) {
+				filtered = append(filtered, search.Result{Item: item})
+			}
+		} else {
+			filtered = search.Search(catalog, text)
+		}
+		list.SetResults(filtered)
 		if len(filtered) > 0 {
 			list.ScrollToTop()
 		}
 	}
 	entry.OnChanged = updateFilter
+	forgetSelected := func(item search.Item) {
+		forgetLaunches(item.ID)
+		updateFilter(entry.Text)
+	}
+	list.SetOnForget(forgetSelected)
+	entry.SetOnForget(func() bool {
+		item, ok := list.SelectedItem()
+		if activePlugin != nil || !ok {
+			return false
+		}
+		forgetSelected(item)
+		return true
+	})
+
 	entry.OnSubmitted = func(string) {
 		// For now we just clear the entry to make it obvious input was received.
 		clearEntry()
 	}
 
 	topBar = container.NewBorder(nil, nil, badge.Object(), nil, entry)
-	content := container.NewBorder(topBar, nil, nil, nil, body)
+	notices := newNoticeArea(func() { showPlugin(diagnosticsPluginID) })
+	diagnostics.SetOnNotice(notices.Post)
+	content := container.NewBorder(topBar, notices.Object(), nil, nil, body)
 	window.SetContent(container.NewPadded(content))
 
 	window.Canvas().AddShortcut(&fynedesktop.CustomShortcut{KeyName: fyne.KeyEscape}, func(fyne.Shortcut) {
@@ -167,10 +225,36 @@ func Run() {
 		}
 	})
 
+	watcher, err := applications.Watch(func(fresh []applications.Application) {
+		fyne.CurrentApp().Driver().DoFromGoroutine(func() {
+			discovered = fresh
+			catalog = buildCatalog(fresh)
+			if activePlugin == nil {
+				updateFilter(entry.Text)
+			}
+		}, false)
+	}, func(err error) {
+		// Every refresh reports the same parse errors again; the log keeps
+		// one entry for each.
+		diagnostics.Warn("Problem discovering applications", err)
+	})
+	if err != nil {
+		diagnostics.Warn("Could not watch application directories", err)
+	} else {
+		defer watcher.Close()
+	}
+
 	window.Canvas().Focus(entry)
 	window.ShowAndRun()
 }
 
+func targets(target string) []string {
+	if target == "" {
+		return nil
+	}
+	return []string{target}
+}
+
 func buildPluginRegistry() map[string]plugins.Info {
 	registry := make(map[string]plugins.Info, len(plugins.All()))
 	for _, info := range plugins.All() {
@@ -179,50 +263,79 @@ func buildPluginRegistry() map[string]plugins.Info {
 	return registry
 }
 
-func pluginApplications() []applications.Application {
-	all := plugins.All()
-	apps := make([]applications.Application, 0, len(all))
-	for _, info := range all {
-		apps = append(apps, applications.Application{
-			Name:     info.Name,
-			Exec:     fmt.Sprintf("plugin:%s", info.ID),
-			Path:     fmt.Sprintf("plugin:%s", info.ID),
-			IconPath: info.IconPath,
-		})
-	}
-	return apps
-}
-
-func launchApplication(window fyne.Window, app applications.Application, showPlugin func(string)) {
-	execCmd := strings.TrimSpace(app.Exec)
-	if strings.HasPrefix(execCmd, "plugin:") {
-		if showPlugin != nil {
-			showPlugin(strings.TrimPrefix(execCmd, "plugin:"))
-		}
-		return
-	}
-	if execCmd == "" {
-		log.Printf("no executable defined for %s", app.Name)
+// launchApplication starts app with the given files or URLs and closes the
+// window. Commands from $PATH run in the terminal according to the
+// configuration; alternate inverts that.
+func launchApplication(window fyne.Window, app applications.Application, alternate bool, targets ...string) {
+	if strings.TrimSpace(app.Exec) == "" {
+		diagnostics.Error(fmt.Sprintf("%s has no command to run", app.Name), nil)
 		return
 	}
 	if runtime.GOOS == "darwin" {
 		bundlePath := strings.TrimSpace(app.Path)
 		if strings.HasSuffix(strings.ToLower(bundlePath), ".app") {
 			if _, err := os.Stat(bundlePath); err == nil {
-				cmd := exec.Command("open", bundlePath)
-				if err := cmd.Start(); err != nil {
-					log.Printf("failed to launch %s: %v", app.Name, err)
-					return
-				}
-				window.Close()
+				startProcess(window, app, append([]string{"open", "-a", bundlePath}, targets...))
 				return
 			}
 		}
 	}
-	cmd := exec.Command("sh", "-c", execCmd)
-	if err := cmd.Start(); err != nil {
-		log.Printf("failed to launch %s: %v", app.Name, err)
+	argv, err := applications.ExpandExec(app, targets...)
+	if err != nil {
+		diagnostics.Error(fmt.Sprintf("Could not launch %s", app.Name), fmt.Errorf("parse exec: %w", err))
+		return
+	}
+	cfg, _ := config.Load()
+	terminal := app.Terminal
+	if app.Source == applications.SourcePath {
+		terminal = cfg.Launch.CommandsInTerminal != alternate
+	}
+	if terminal {
+		argv, err = applications.TerminalCommand(cfg.Launch.Terminal, argv)
+		if err != nil {
+			diagnostics.Error(fmt.Sprintf("Could not launch %s", app.Name), err)
+			return
+		}
+	}
+	startProcess(window, app, argv)
+}
+
+// startProcess runs argv detached and hides the window. The launcher only
+// exits once the process has survived its first second, so an early crash is
+// still reported.
+func startProcess(window fyne.Window, app applications.Application, argv []string) {
+	name := app.ID
+	if name == "" {
+		name = app.Name
+	}
+	proc, err := process.Start(process.Spec{
+		Name:          name,
+		Argv:          argv,
+		Dir:           app.WorkingDir,
+		Title:         app.Name,
+		Icon:          app.IconName,
+		StartupNotify: app.StartupNotify,
+		WMClass:       app.StartupWMClass,
+	})
+	if err != nil {
+		diagnostics.Error(fmt.Sprintf("Could not launch %s", app.Name), err)
 		return
 	}
-	window.Close()
+	if proc.LogErr != nil {
+		diagnostics.Warn(fmt.Sprintf("Could not open the log for %s", app.Name), proc.LogErr)
+	}
+	window.Hide()
+	go func() {
+		if err := proc.CheckEarly(process.EarlyFailureWindow); err != nil {
+			if proc.LogPath != "" {
+				err = fmt.Errorf("%w\nlog: %s", err, proc.LogPath)
+			}
+			// Bring the window back so the failure is seen.
+			fyne.CurrentApp().Driver().DoFromGoroutine(window.Show, false)
+			diagnostics.Error(fmt.Sprintf("%s exited right after starting", app.Name), err)
+			return
+		}
+		recordLaunch(applicationID(app))
+		fyne.CurrentApp().Driver().DoFromGoroutine(window.Close, false)
+	}()
 }
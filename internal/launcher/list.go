@@ -1,121 +1,188 @@
 package launcher
 
 import (
+	"strings"
+
 	"fyne.io/fyne/v2"
-	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 
-	"github.com/SagenKoder/launcher/internal/applications"
+	"github.com/SagenKoder/launcher/internal/search"
 	"github.com/SagenKoder/launcher/internal/ui"
 )
 
+// launcherList shows the matching items on a virtualized widget.List, so
+// only the rows in the viewport exist no matter how many items match. The
+// selection is drawn by the rows themselves and kept scrolled into view.
 type launcherList struct {
 	widget.BaseWidget
-	box        *fyne.Container
-	scroll     *container.Scroll
-	items      []*ui.AppListItem
-	apps       []applications.Application
+	list       *widget.List
+	results    []search.Result
 	selected   int
 	onEscape   func()
-	onActivate func(app applications.Application)
+	onActivate func(item search.Item)
+	onForget   func(item search.Item)
 }
 
 func newLauncherList(onEscape func()) *launcherList {
 	l := &launcherList{onEscape: onEscape, selected: -1}
+	l.list = widget.NewList(
+		func() int { return len(l.results) },
+		func() fyne.CanvasObject { return ui.NewAppListItem() },
+		l.updateItem,
+	)
 	l.ExtendBaseWidget(l)
 	return l
 }
 
 func (l *launcherList) CreateRenderer() fyne.WidgetRenderer {
-	l.box = container.NewVBox()
-	l.scroll = container.NewVScroll(l.box)
-	return widget.NewSimpleRenderer(l.scroll)
+	return widget.NewSimpleRenderer(l.list)
 }
 
-func (l *launcherList) SetApplications(apps []applications.Application) {
-	if l.box == nil {
+// updateItem binds a recycled row to the result at id.
+func (l *launcherList) updateItem(id widget.ListItemID, obj fyne.CanvasObject) {
+	if id < 0 || id >= len(l.results) {
 		return
 	}
-	l.box.Objects = l.box.Objects[:0]
-	l.items = l.items[:0]
-	l.apps = append(l.apps[:0], apps...)
-	for idx, app := range apps {
-		item := ui.NewAppListItem()
-		item.Set(iconResource(app.IconPath), app.Name)
-		item.SetOnTapped(l.makeSelectHandler(idx))
-		l.box.Objects = append(l.box.Objects, item)
-		l.items = append(l.items, item)
+	item := obj.(*ui.AppListItem)
+	res := l.results[id]
+	// A row showing the placeholder is refreshed once its icon is loaded;
+	// by then it may be bound to another item, which is harmless.
+	icon := icons.Load(res.Item.IconPath, func(fyne.Resource) { l.list.RefreshItem(id) })
+	item.SetWithMatches(icon, res.Item.Title, res.Positions)
+	item.SetDetail(res.Item.Detail)
+	item.SetSelected(id == l.selected)
+	item.SetOnTapped(func() { l.selectIndex(id) })
+	item.SetOnDoubleTapped(func() {
+		l.selectIndex(id)
+		l.ActivateSelection()
+	})
+	item.SetOnTappedSecondary(func(ev *fyne.PointEvent) {
+		l.selectIndex(id)
+		l.showMenu(item, ev.AbsolutePosition)
+	})
+}
+
+// showMenu shows the context menu of the selected row, which offers the
+// item's actions besides opening it.
+func (l *launcherList) showMenu(row fyne.CanvasObject, pos fyne.Position) {
+	selected, ok := l.SelectedItem()
+	if !ok {
+		return
+	}
+	entries := []*fyne.MenuItem{fyne.NewMenuItem("Open", l.ActivateSelection)}
+	for _, action := range selected.Actions {
+		title := strings.TrimPrefix(action.Title, selected.Title+": ")
+		entries = append(entries, fyne.NewMenuItem(title, func() {
+			if l.onActivate != nil {
+				l.onActivate(action)
+			}
+		}))
+	}
+	if l.onForget != nil {
+		entries = append(entries, fyne.NewMenuItemSeparator(),
+			fyne.NewMenuItem("Forget from history", func() { l.onForget(selected) }))
 	}
-	if len(apps) > 0 {
+	canvas := fyne.CurrentApp().Driver().CanvasForObject(row)
+	if canvas == nil {
+		return
+	}
+	widget.ShowPopUpMenuAtPosition(fyne.NewMenu("", entries...), canvas, pos)
+}
+
+// SetResults shows results with the first one selected, highlighting the
+// matched characters of each title.
+func (l *launcherList) SetResults(results []search.Result) {
+	l.results = append(l.results[:0], results...)
+	l.resetSelection()
+}
+
+func (l *launcherList) resetSelection() {
+	if len(l.results) > 0 {
 		l.selected = 0
-		l.updateSelection()
 	} else {
 		l.selected = -1
 	}
-	l.box.Refresh()
+	l.list.Refresh()
 }
 
 func (l *launcherList) ScrollToTop() {
-	if l.scroll != nil {
-		l.scroll.ScrollToTop()
-	}
+	l.list.ScrollToTop()
 }
 
-func (l *launcherList) moveSelection(delta int) {
-	if len(l.apps) == 0 {
+// MoveSelection moves the selection by delta rows, stopping at either end.
+func (l *launcherList) MoveSelection(delta int) {
+	if len(l.results) == 0 {
 		return
 	}
-	next := l.selected
-	if next < 0 {
-		next = 0
-	} else {
-		next += delta
-		if next < 0 {
-			next = 0
-		}
-		if next >= len(l.apps) {
-			next = len(l.apps) - 1
-		}
-	}
-	if next == l.selected {
+	if l.selected < 0 {
+		l.selectIndex(0)
 		return
 	}
-	l.selected = next
-	l.updateSelection()
+	l.selectIndex(min(max(l.selected+delta, 0), len(l.results)-1))
 }
 
-func (l *launcherList) updateSelection() {
-	for idx, item := range l.items {
-		item.SetSelected(idx == l.selected)
+// Navigate handles the keys that move the selection and reports whether key
+// was one of them.
+func (l *launcherList) Navigate(key fyne.KeyName) bool {
+	switch key {
+	case fyne.KeyDown:
+		l.MoveSelection(1)
+	case fyne.KeyUp:
+		l.MoveSelection(-1)
+	case fyne.KeyPageDown:
+		l.MoveSelection(l.pageSize())
+	case fyne.KeyPageUp:
+		l.MoveSelection(-l.pageSize())
+	case fyne.KeyHome:
+		l.MoveSelection(-len(l.results))
+	case fyne.KeyEnd:
+		l.MoveSelection(len(l.results))
+	default:
+		return false
 	}
+	return true
 }
 
-func (l *launcherList) MoveSelection(delta int) {
-	l.moveSelection(delta)
+// pageSize is the number of rows that fit in the viewport.
+func (l *launcherList) pageSize() int {
+	row := ui.NewAppListItem().MinSize().Height + theme.Padding()
+	return max(1, int(l.list.Size().Height/row)-1)
 }
 
-func (l *launcherList) makeSelectHandler(idx int) func() {
-	return func() {
-		l.selected = idx
-		l.updateSelection()
+func (l *launcherList) selectIndex(idx int) {
+	if idx < 0 || idx >= len(l.results) {
+		return
 	}
+	previous := l.selected
+	l.selected = idx
+	if previous != idx && previous >= 0 {
+		l.list.RefreshItem(previous)
+	}
+	l.list.RefreshItem(idx)
+	l.list.ScrollTo(idx)
 }
 
-func (l *launcherList) SetOnActivate(fn func(app applications.Application)) {
+func (l *launcherList) SetOnActivate(fn func(item search.Item)) {
 	l.onActivate = fn
 }
 
+// SetOnForget adds a "Forget from history" entry to the context menu.
+func (l *launcherList) SetOnForget(fn func(item search.Item)) {
+	l.onForget = fn
+}
+
 func (l *launcherList) ActivateSelection() {
-	if l.onActivate != nil && l.selected >= 0 && l.selected < len(l.apps) {
-		l.onActivate(l.apps[l.selected])
+	if l.onActivate != nil && l.selected >= 0 && l.selected < len(l.results) {
+		l.onActivate(l.results[l.selected].Item)
 	}
 }
 
-func (l *launcherList) SelectedApplication() (applications.Application, bool) {
-	if l.selected >= 0 && l.selected < len(l.apps) {
-		return l.apps[l.selected], true
+func (l *launcherList) SelectedItem() (search.Item, bool) {
+	if l.selected >= 0 && l.selected < len(l.results) {
+		return l.results[l.selected].Item, true
 	}
-	return applications.Application{}, false
+	return search.Item{}, false
 }
 
 func (l *launcherList) TypedKey(event *fyne.KeyEvent) {
@@ -124,11 +191,9 @@ func (l *launcherList) TypedKey(event *fyne.KeyEvent) {
 		if l.onEscape != nil {
 			l.onEscape()
 		}
-	case fyne.KeyDown:
-		l.moveSelection(1)
-	case fyne.KeyUp:
-		l.moveSelection(-1)
 	case fyne.KeyReturn, fyne.KeyEnter:
 		l.ActivateSelection()
+	default:
+		l.Navigate(event.Name)
 	}
 }
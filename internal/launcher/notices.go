@@ -0,0 +1,110 @@
+package launcher
+
+import (
+	"fmt"
+	"image/color"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// noticeArea is a non-modal strip at the bottom of the window showing the
+// oldest unacknowledged error. Further errors queue behind it.
+type noticeArea struct {
+	container  *fyne.Container
+	background *canvas.Rectangle
+	summary    *widget.Label
+	detail     *widget.Label
+	more       *widget.Label
+	toggle     *widget.Button
+	queue      []diagnostic
+	expanded   bool
+}
+
+func newNoticeArea(onDiagnostics func()) *noticeArea {
+	n := &noticeArea{
+		background: canvas.NewRectangle(theme.ErrorColor()),
+		summary:    widget.NewLabel(""),
+		detail:     widget.NewLabel(""),
+		more:       widget.NewLabel(""),
+	}
+	n.background.CornerRadius = theme.InputRadiusSize()
+	n.background.FillColor = withAlpha(theme.ErrorColor(), 0x30)
+	n.summary.TextStyle = fyne.TextStyle{Bold: true}
+	n.summary.Truncation = fyne.TextTruncateEllipsis
+	n.detail.Wrapping = fyne.TextWrapWord
+	n.detail.Hide()
+	n.more.Importance = widget.LowImportance
+
+	n.toggle = widget.NewButton("Details", func() {
+		n.expanded = !n.expanded
+		n.update()
+	})
+	n.toggle.Importance = widget.LowImportance
+	diagnosticsButton := widget.NewButton("Diagnostics", onDiagnostics)
+	diagnosticsButton.Importance = widget.LowImportance
+	dismiss := widget.NewButtonWithIcon("", theme.CancelIcon(), n.dismiss)
+	dismiss.Importance = widget.LowImportance
+
+	buttons := container.NewHBox(n.more, n.toggle, diagnosticsButton, dismiss)
+	header := container.NewBorder(nil, nil, widget.NewIcon(theme.ErrorIcon()), buttons, n.summary)
+	n.container = container.NewMax(n.background, container.NewPadded(container.NewVBox(header, n.detail)))
+	n.container.Hide()
+	return n
+}
+
+// Post queues a notice. It must be called on the UI goroutine.
+func (n *noticeArea) Post(entry diagnostic) {
+	n.queue = append(n.queue, entry)
+	n.update()
+}
+
+func (n *noticeArea) dismiss() {
+	if len(n.queue) > 0 {
+		n.queue = n.queue[1:]
+	}
+	n.expanded = false
+	n.update()
+}
+
+func (n *noticeArea) update() {
+	if len(n.queue) == 0 {
+		n.container.Hide()
+		return
+	}
+	current := n.queue[0]
+	n.summary.SetText(current.summary)
+	n.detail.SetText(current.detail)
+	if current.detail == "" {
+		n.toggle.Hide()
+	} else {
+		n.toggle.Show()
+	}
+	if n.expanded && current.detail != "" {
+		n.toggle.SetText("Hide")
+		n.detail.Show()
+	} else {
+		n.toggle.SetText("Details")
+		n.detail.Hide()
+	}
+	if len(n.queue) > 1 {
+		n.more.SetText(fmt.Sprintf("+%d more", len(n.queue)-1))
+		n.more.Show()
+	} else {
+		n.more.Hide()
+	}
+	n.container.Show()
+	n.container.Refresh()
+}
+
+func (n *noticeArea) Object() fyne.CanvasObject {
+	return n.container
+}
+
+func withAlpha(c color.Color, alpha uint8) color.Color {
+	r, g, b, _ := c.RGBA()
+	return color.NRGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: alpha}
+}
@@ -12,7 +12,6 @@ import (
 	"strings"
 	"sync"
 
-	"github.com/SagenKoder/launcher/internal/applications"
 	"github.com/SagenKoder/launcher/internal/config"
 )
 
@@ -20,7 +19,7 @@ func init() {
 	Register(Info{
 		ID:            "chat",
 		Name:          "AI Chat",
-		IconPath:      applications.DebugResolveIcon("dialog-information"),
+		Icon:          "dialog-information",
 		Intro:         "Ask the assistant anything. Responses stream in real time.",
 		Hint:          "Ask the AI",
 		CloseOnSubmit: false,
@@ -7,9 +7,11 @@ import (
 )
 
 type Info struct {
-	ID             string
-	Name           string
-	IconPath       string
+	ID   string
+	Name string
+	// Icon is a theme icon name or an absolute path to an image. It is
+	// resolved when the plugin is first shown, not at registration.
+	Icon           string
 	Intro          string
 	Hint           string
 	OnInit         func() (string, error)
@@ -21,7 +23,10 @@ type Info struct {
 
 type StreamFunc func(ctx context.Context, input string, emit func(markdown string, done bool)) error
 
-var registry []Info
+var (
+	registry   []Info
+	loadErrors []error
+)
 
 func Register(info Info) {
 	registry = append(registry, info)
@@ -31,6 +36,16 @@ func All() []Info {
 	return append([]Info(nil), registry...)
 }
 
+// Errors returns the problems found while registering plugins, such as an
+// unreadable config file or an incomplete link entry.
+func Errors() []error {
+	return append([]error(nil), loadErrors...)
+}
+
+func reportError(err error) {
+	loadErrors = append(loadErrors, err)
+}
+
 func openURL(link string) error {
 	var cmd *exec.Cmd
 	switch runtime.GOOS {
@@ -1,34 +1,36 @@
 package plugins
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 	"strings"
 
-	"github.com/SagenKoder/launcher/internal/applications"
 	"github.com/SagenKoder/launcher/internal/config"
 )
 
 func init() {
 	cfg, err := config.Load()
 	if err != nil {
+		if !errors.Is(err, config.ErrNotFound) {
+			reportError(fmt.Errorf("links: %w", err))
+		}
 		return
 	}
 
-	for _, link := range cfg.Links {
+	for i, link := range cfg.Links {
 		if strings.TrimSpace(link.Name) == "" || strings.TrimSpace(link.URL) == "" {
+			reportError(fmt.Errorf("links: entry %d in %s needs both a name and a url", i+1, config.Path()))
 			continue
 		}
 
 		linkCopy := link
-		iconPath := applications.DebugResolveIcon(linkCopy.Icon)
-
 		replacement := strings.TrimSpace(linkCopy.Replacement)
 		if replacement == "" {
 			Register(Info{
 				ID:            "link-" + slugify(linkCopy.Name),
 				Name:          linkCopy.Name,
-				IconPath:      iconPath,
+				Icon:          linkCopy.Icon,
 				Intro:         fmt.Sprintf("Opening %s…", linkCopy.Name),
 				CloseOnSubmit: true,
 				OnInit: func() (string, error) {
@@ -43,7 +45,7 @@ func init() {
 		Register(Info{
 			ID:            "link-" + slugify(linkCopy.Name),
 			Name:          linkCopy.Name,
-			IconPath:      iconPath,
+			Icon:          linkCopy.Icon,
 			Intro:         fmt.Sprintf("Enter text to open %s.", linkCopy.Name),
 			Hint:          fmt.Sprintf("Search %s", linkCopy.Name),
 			CloseOnSubmit: true,
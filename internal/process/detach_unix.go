@@ -0,0 +1,14 @@
+//go:build unix
+
+package process
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detach starts the child in a new session so it has no controlling terminal
+// and is not signalled along with the launcher's process group.
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
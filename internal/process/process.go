@@ -0,0 +1,218 @@
+// Package process starts launched applications detached from the launcher,
+// reaps them, and records their output in per-application log files.
+package process
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/SagenKoder/launcher/internal/config"
+)
+
+// EarlyFailureWindow is how long after starting an unsuccessful exit is
+// treated as a failure to launch rather than the application quitting.
+const EarlyFailureWindow = time.Second
+
+// maxLogSize caps a log file; larger logs are truncated on the next launch.
+const maxLogSize = 1 << 20
+
+// Process is a running or exited application started by Start.
+type Process struct {
+	// LogPath is the file receiving the process's stdout and stderr. It is
+	// empty when the log could not be opened.
+	LogPath string
+	// LogErr is why the log could not be opened, in which case the output is
+	// discarded rather than failing the launch.
+	LogErr error
+
+	cmd       *exec.Cmd
+	logStart  int64
+	done      chan struct{}
+	err       error
+	startedAt time.Time
+}
+
+// Spec describes an application to start.
+type Spec struct {
+	// Name identifies the application, typically its desktop file ID. It
+	// names the log file.
+	Name string
+	Argv []string
+	Dir  string
+	// Title, Icon, StartupNotify and WMClass come from the desktop entry and
+	// are passed to the StartupNotifier.
+	Title         string
+	Icon          string
+	StartupNotify bool
+	WMClass       string
+}
+
+// Start runs the application in its own session with stdin closed and stdout
+// and stderr appended to its log file. The environment carries the startup
+// notification produced by the current StartupNotifier. The process is reaped
+// in the background, so callers never need to call Wait.
+func Start(spec Spec) (*Process, error) {
+	argv := spec.Argv
+	if len(argv) == 0 {
+		return nil, errors.New("empty command")
+	}
+	logFile, logPath, logErr := openLog(spec.Name)
+	if logErr != nil {
+		var err error
+		logFile, err = os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+		if err != nil {
+			return nil, logErr
+		}
+		logPath = ""
+	}
+	defer logFile.Close()
+	fmt.Fprintf(logFile, "==> %s %q\n", time.Now().Format(time.RFC3339), argv)
+	logStart, err := logFile.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Dir = spec.Dir
+	cmd.Env = childEnv(notifier.Env(spec))
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	detach(cmd)
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(logFile, "==> start failed: %v\n", err)
+		return nil, err
+	}
+
+	p := &Process{
+		LogPath:   logPath,
+		LogErr:    logErr,
+		cmd:       cmd,
+		logStart:  logStart,
+		done:      make(chan struct{}),
+		startedAt: time.Now(),
+	}
+	go func() {
+		p.err = cmd.Wait()
+		close(p.done)
+	}()
+	return p, nil
+}
+
+// Pid returns the process ID.
+func (p *Process) Pid() int {
+	return p.cmd.Process.Pid
+}
+
+// Done is closed once the process has exited and been reaped.
+func (p *Process) Done() <-chan struct{} {
+	return p.done
+}
+
+// Wait blocks until the process exits and returns its exit error.
+func (p *Process) Wait() error {
+	<-p.done
+	return p.err
+}
+
+// CheckEarly waits until the process has been running for window and returns
+// an error if it exited unsuccessfully before then. A process that is still
+// running, or exited with status zero, is not a failure: many applications
+// hand off to an existing instance and exit immediately.
+func (p *Process) CheckEarly(window time.Duration) error {
+	timer := time.NewTimer(time.Until(p.startedAt.Add(window)))
+	defer timer.Stop()
+	select {
+	case <-p.done:
+	case <-timer.C:
+		return nil
+	}
+	if p.err == nil {
+		return nil
+	}
+	if line := p.lastOutputLine(); line != "" {
+		return fmt.Errorf("%w: %s", p.err, line)
+	}
+	return p.err
+}
+
+// lastOutputLine returns the last non-empty line the process wrote, which
+// usually explains why it failed.
+func (p *Process) lastOutputLine() string {
+	file, err := os.Open(p.LogPath)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	const tail = 4096
+	info, err := file.Stat()
+	if err != nil {
+		return ""
+	}
+	// The log may have been truncated since, by another launch of the same
+	// application.
+	start := min(max(p.logStart, info.Size()-tail), info.Size())
+	data := make([]byte, info.Size()-start)
+	if _, err := file.ReadAt(data, start); err != nil && !errors.Is(err, io.EOF) {
+		return ""
+	}
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	return strings.TrimSpace(string(lines[len(lines)-1]))
+}
+
+// LogDir returns the directory holding the per-application logs.
+func LogDir() (string, error) {
+	dir, err := config.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "logs"), nil
+}
+
+func openLog(name string) (*os.File, string, error) {
+	dir, err := LogDir()
+	if err != nil {
+		return nil, "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, "", err
+	}
+	path := filepath.Join(dir, logName(name)+".log")
+	flags := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	if info, err := os.Stat(path); err == nil && info.Size() > maxLogSize {
+		flags |= os.O_TRUNC
+	}
+	file, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return nil, "", err
+	}
+	return file, path, nil
+}
+
+// logName turns an application ID or name into a safe file name.
+func logName(name string) string {
+	name = strings.TrimSuffix(name, ".desktop")
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+		if b.Len() >= 64 {
+			break
+		}
+	}
+	result := strings.Trim(b.String(), "._")
+	if result == "" {
+		return "command"
+	}
+	return result
+}
@@ -0,0 +1,53 @@
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStartWithoutLog(t *testing.T) {
+	// A file where the state directory should be makes the log unopenable.
+	state := filepath.Join(t.TempDir(), "state")
+	if err := os.WriteFile(state, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("XDG_STATE_HOME", state)
+
+	proc, err := Start(Spec{Name: "no-log", Argv: []string{"sh", "-c", "echo output; exit 3"}})
+	if err != nil {
+		t.Fatalf("Start = %v, want the launch to go ahead", err)
+	}
+	if proc.LogErr == nil || proc.LogPath != "" {
+		t.Errorf("LogErr = %v, LogPath = %q; want the open error and no path", proc.LogErr, proc.LogPath)
+	}
+	if err := proc.CheckEarly(10 * time.Second); err == nil {
+		t.Error("CheckEarly = nil, want the exit status")
+	}
+}
+
+func TestLastOutputLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		log      string
+		logStart int64
+		want     string
+	}{
+		{"last line", "==> header\nfirst\nsecond\n\n", 11, "second"},
+		{"nothing written", "==> header\n", 11, ""},
+		{"truncated since", "short\n", 100, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log")
+			if err := os.WriteFile(path, []byte(tt.log), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			p := &Process{LogPath: path, logStart: tt.logStart}
+			if got := p.lastOutputLine(); got != tt.want {
+				t.Errorf("lastOutputLine = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
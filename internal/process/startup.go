@@ -0,0 +1,230 @@
+package process
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Environment variables carrying startup notification to a child.
+const (
+	startupIDEnv       = "DESKTOP_STARTUP_ID"
+	activationTokenEnv = "XDG_ACTIVATION_TOKEN"
+)
+
+// StartupNotifier decides how a launched application learns that it was
+// started on the user's behalf, so the window manager or compositor lets its
+// first window take focus.
+type StartupNotifier interface {
+	// Env returns the variables to add to the child's environment, such as
+	// DESKTOP_STARTUP_ID or XDG_ACTIVATION_TOKEN.
+	Env(spec Spec) []string
+}
+
+var notifier StartupNotifier = newSessionNotifier(os.Getenv)
+
+// SetStartupNotifier replaces the notifier used by Start and returns the
+// previous one. It exists so tests can observe or fake startup IDs.
+func SetStartupNotifier(n StartupNotifier) StartupNotifier {
+	previous := notifier
+	notifier = n
+	return previous
+}
+
+// sessionNotifier takes part in startup notification on the displays the
+// launcher runs on.
+//
+// On X11, for applications declaring StartupNotify, it broadcasts the "new:"
+// message that starts a startup sequence and passes the sequence's ID in
+// DESKTOP_STARTUP_ID. The ID ends in _TIME and the server time of the launch,
+// which window managers use for focus stealing prevention. The application
+// ends the sequence once its window is shown.
+//
+// On Wayland it requests an xdg_activation_v1 token and passes it in
+// XDG_ACTIVATION_TOKEN. The toolkit does not expose its compositor
+// connection, so the request comes from a connection of its own and carries
+// no input serial or surface; compositors that require those may hand out a
+// token that does not grant focus. If no token can be had, the token the
+// launcher itself was started with, typically handed over by the hotkey
+// daemon, is passed on to the first application launched instead.
+//
+// Both are best effort: when a display cannot be reached the child is still
+// started, on X11 with an ID lacking the timestamp.
+type sessionNotifier struct {
+	x11      *x11Display
+	wayland  string
+	hostname string
+	// dial connects to the display servers; tests replace it.
+	dial func(network, address string) (net.Conn, error)
+
+	mu    sync.Mutex
+	token string
+	seq   atomic.Uint64
+}
+
+// displayTimeout bounds each exchange with a display server, so a hung
+// server delays a launch by at most this long.
+const displayTimeout = time.Second
+
+func newSessionNotifier(getenv func(string) string) *sessionNotifier {
+	hostname, _ := os.Hostname()
+	n := &sessionNotifier{
+		hostname: hostname,
+		token:    getenv(activationTokenEnv),
+		dial: func(network, address string) (net.Conn, error) {
+			return net.DialTimeout(network, address, displayTimeout)
+		},
+	}
+	if display := getenv("WAYLAND_DISPLAY"); display != "" {
+		n.wayland = waylandSocket(display, getenv("XDG_RUNTIME_DIR"))
+	}
+	if display := getenv("DISPLAY"); display != "" {
+		n.x11, _ = parseX11Display(display, defaultXauthority(getenv))
+	}
+	return n
+}
+
+func (n *sessionNotifier) Env(spec Spec) []string {
+	var env []string
+	if n.wayland != "" {
+		token, err := n.activationToken(spec)
+		if err != nil {
+			token = n.inheritedToken()
+		}
+		if token != "" {
+			env = append(env, activationTokenEnv+"="+token)
+		}
+	}
+	// XWayland applications read DESKTOP_STARTUP_ID too, so it is set whenever
+	// there is an X display.
+	if n.x11 != nil && spec.StartupNotify {
+		id, err := n.announceStartup(spec)
+		if err != nil {
+			id = n.startupID(spec)
+		}
+		env = append(env, startupIDEnv+"="+id)
+	}
+	return env
+}
+
+// activationToken requests a token for the application from the compositor.
+func (n *sessionNotifier) activationToken(spec Spec) (string, error) {
+	conn, err := n.dial("unix", n.wayland)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(displayTimeout))
+	return requestActivationToken(conn, strings.TrimSuffix(spec.Name, ".desktop"))
+}
+
+// inheritedToken returns the launcher's own activation token the first time
+// it is called. Tokens are single use, so later calls return "".
+func (n *sessionNotifier) inheritedToken() string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	token := n.token
+	n.token = ""
+	return token
+}
+
+// announceStartup broadcasts the "new:" message for a startup sequence and
+// returns the sequence's ID.
+func (n *sessionNotifier) announceStartup(spec Spec) (string, error) {
+	conn, err := n.dial(n.x11.network, n.x11.address)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(displayTimeout))
+	c, err := connectX11(conn, n.x11, n.hostname)
+	if err != nil {
+		return "", err
+	}
+	begin, err := c.internAtom("_NET_STARTUP_INFO_BEGIN")
+	if err != nil {
+		return "", err
+	}
+	info, err := c.internAtom("_NET_STARTUP_INFO")
+	if err != nil {
+		return "", err
+	}
+	window, err := c.createWindow()
+	if err != nil {
+		return "", err
+	}
+	timestamp, err := c.serverTime(window, info)
+	if err != nil {
+		return "", err
+	}
+	id := fmt.Sprintf("%s_TIME%d", n.startupID(spec), timestamp)
+	if err := c.broadcast(window, begin, info, startupMessage(id, spec, n.x11.screen)); err != nil {
+		return "", err
+	}
+	return id, c.sync()
+}
+
+// startupMessage builds the "new:" message announcing a startup sequence.
+func startupMessage(id string, spec Spec, screen int) string {
+	name := spec.Title
+	if name == "" {
+		name = spec.Name
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "new: ID=%s NAME=%s SCREEN=%d", quoteStartupValue(id), quoteStartupValue(name), screen)
+	if len(spec.Argv) > 0 {
+		fmt.Fprintf(&b, " BIN=%s", quoteStartupValue(filepath.Base(spec.Argv[0])))
+	}
+	if spec.Icon != "" {
+		fmt.Fprintf(&b, " ICON=%s", quoteStartupValue(spec.Icon))
+	}
+	if spec.WMClass != "" {
+		fmt.Fprintf(&b, " WMCLASS=%s", quoteStartupValue(spec.WMClass))
+	}
+	return b.String()
+}
+
+// quoteStartupValue quotes a message value, escaping quotes and backslashes.
+func quoteStartupValue(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// startupID builds a unique ID from the launcher's PID and host, the program
+// and a sequence number, as the startup notification specification suggests.
+func (n *sessionNotifier) startupID(spec Spec) string {
+	program := spec.WMClass
+	if program == "" {
+		program = logName(spec.Name)
+	}
+	return sanitizeStartupID(fmt.Sprintf("launcher-%d-%s-%s-%d", os.Getpid(), n.hostname, program, n.seq.Add(1)))
+}
+
+// sanitizeStartupID removes characters that are not allowed unquoted in
+// startup notification messages.
+func sanitizeStartupID(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '"' || r == '\\' || r == '=' || r > '~' {
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+// childEnv returns the launcher's environment without its own startup
+// variables, which belong to the launcher's window, plus extra.
+func childEnv(extra []string) []string {
+	env := os.Environ()
+	kept := env[:0]
+	for _, kv := range env {
+		if strings.HasPrefix(kv, startupIDEnv+"=") || strings.HasPrefix(kv, activationTokenEnv+"=") {
+			continue
+		}
+		kept = append(kept, kv)
+	}
+	return append(kept, extra...)
+}
@@ -0,0 +1,398 @@
+package process
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionNotifierEnv(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		spec Spec
+		// want lists the expected variable names for two launches in a row.
+		want [2][]string
+	}{
+		{
+			name: "X11",
+			env:  map[string]string{"DISPLAY": ":0"},
+			spec: Spec{Name: "org.example.Editor.desktop", StartupNotify: true},
+			want: [2][]string{{startupIDEnv}, {startupIDEnv}},
+		},
+		{
+			name: "X11 without StartupNotify",
+			env:  map[string]string{"DISPLAY": ":0"},
+			spec: Spec{Name: "org.example.Editor.desktop"},
+		},
+		{
+			name: "Wayland passes its own token on once without a compositor",
+			env:  map[string]string{"WAYLAND_DISPLAY": "wayland-0", activationTokenEnv: "token-1"},
+			spec: Spec{Name: "org.example.Editor.desktop"},
+			want: [2][]string{{activationTokenEnv}, nil},
+		},
+		{
+			name: "Wayland without a token",
+			env:  map[string]string{"WAYLAND_DISPLAY": "wayland-0"},
+			spec: Spec{Name: "org.example.Editor.desktop", StartupNotify: true},
+		},
+		{
+			name: "XWayland",
+			env:  map[string]string{"WAYLAND_DISPLAY": "wayland-0", "DISPLAY": ":0", activationTokenEnv: "token-1"},
+			spec: Spec{Name: "org.example.Editor.desktop", StartupNotify: true},
+			want: [2][]string{{activationTokenEnv, startupIDEnv}, {startupIDEnv}},
+		},
+		{
+			name: "no display",
+			spec: Spec{Name: "org.example.Editor.desktop", StartupNotify: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := newSessionNotifier(func(key string) string { return tt.env[key] })
+			n.dial = func(string, string) (net.Conn, error) {
+				return nil, errors.New("no display server")
+			}
+			seen := make(map[string]bool)
+			for i, want := range tt.want {
+				env := n.Env(tt.spec)
+				var names []string
+				for _, kv := range env {
+					name, value, _ := strings.Cut(kv, "=")
+					names = append(names, name)
+					if name == activationTokenEnv && value != tt.env[activationTokenEnv] {
+						t.Errorf("launch %d: token = %q, want the inherited one", i+1, value)
+					}
+					if name == startupIDEnv {
+						if seen[value] {
+							t.Errorf("launch %d: startup ID %q was reused", i+1, value)
+						}
+						seen[value] = true
+					}
+				}
+				if strings.Join(names, " ") != strings.Join(want, " ") {
+					t.Errorf("launch %d: Env = %q, want variables %q", i+1, env, want)
+				}
+			}
+		})
+	}
+}
+
+func TestStartupIDUsesWMClass(t *testing.T) {
+	n := newSessionNotifier(func(string) string { return "" })
+	n.hostname = "host name"
+	id := n.startupID(Spec{Name: "editor", WMClass: "Editor Main"})
+	if !strings.Contains(id, "-host_name-Editor_Main-1") {
+		t.Errorf("startupID = %q, want the sanitized host and WM class", id)
+	}
+}
+
+func TestAnnounceStartup(t *testing.T) {
+	xauthority := filepath.Join(t.TempDir(), "Xauthority")
+	var auth []byte
+	for _, entry := range []struct {
+		family          uint16
+		address, number string
+		secret          string
+	}{
+		{256, "other", "0", "wrong-host"},
+		{256, "host", "1", "wrong-display"},
+		{256, "host", "0", "cookie"},
+	} {
+		auth = binary.BigEndian.AppendUint16(auth, entry.family)
+		for _, field := range []string{entry.address, entry.number, "MIT-MAGIC-COOKIE-1", entry.secret} {
+			auth = binary.BigEndian.AppendUint16(auth, uint16(len(field)))
+			auth = append(auth, field...)
+		}
+	}
+	if err := os.WriteFile(xauthority, auth, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	n := newSessionNotifier(func(key string) string {
+		return map[string]string{"DISPLAY": ":0", "XAUTHORITY": xauthority}[key]
+	})
+	n.hostname = "host"
+	server := &fakeX11{time: 4242}
+	n.dial = func(network, address string) (net.Conn, error) {
+		if network != "unix" || address != "/tmp/.X11-unix/X0" {
+			t.Errorf("dialed %s %s, want the display 0 socket", network, address)
+		}
+		client, conn := net.Pipe()
+		go server.serve(conn)
+		return client, nil
+	}
+
+	env := n.Env(Spec{
+		Name:          "org.example.Editor.desktop",
+		Title:         `My "Editor"`,
+		Icon:          "editor",
+		Argv:          []string{"/usr/bin/editor", "--new-window"},
+		StartupNotify: true,
+		WMClass:       "Editor",
+	})
+	if len(env) != 1 || !strings.HasPrefix(env[0], startupIDEnv+"=") {
+		t.Fatalf("Env = %q, want a startup ID", env)
+	}
+	id := strings.TrimPrefix(env[0], startupIDEnv+"=")
+	if !strings.HasSuffix(id, "_TIME4242") {
+		t.Errorf("startup ID %q does not carry the server time", id)
+	}
+	if server.cookie != "cookie" {
+		t.Errorf("connected with cookie %q, want the one for this host and display", server.cookie)
+	}
+	want := `new: ID="` + id + `" NAME="My \"Editor\"" SCREEN=0 BIN="editor" ICON="editor" WMCLASS="Editor"`
+	if len(server.messages) != 1 || server.messages[0] != want {
+		t.Errorf("broadcast %q, want %q", server.messages, want)
+	}
+}
+
+func TestParseX11Display(t *testing.T) {
+	tests := []struct {
+		display string
+		want    x11Display
+	}{
+		{":0", x11Display{network: "unix", address: "/tmp/.X11-unix/X0", number: "0"}},
+		{"unix:1.2", x11Display{network: "unix", address: "/tmp/.X11-unix/X1", number: "1", screen: 2}},
+		{"localhost:10.0", x11Display{network: "tcp", address: "localhost:6010", number: "10"}},
+		{"/private/tmp/com.apple.launchd.x/org.xquartz:0", x11Display{network: "unix", address: "/private/tmp/com.apple.launchd.x/org.xquartz:0", number: "0"}},
+	}
+	for _, tt := range tests {
+		got, err := parseX11Display(tt.display, "")
+		if err != nil || *got != tt.want {
+			t.Errorf("parseX11Display(%q) = %+v, %v; want %+v", tt.display, got, err, tt.want)
+		}
+	}
+	for _, display := range []string{"", "host", ":x", ":0.x"} {
+		if _, err := parseX11Display(display, ""); err == nil {
+			t.Errorf("parseX11Display(%q) succeeded", display)
+		}
+	}
+}
+
+// fakeX11 is an X server implementing the requests announceStartup sends.
+type fakeX11 struct {
+	time     uint32
+	cookie   string
+	messages []string
+}
+
+func (f *fakeX11) serve(conn net.Conn) {
+	defer conn.Close()
+	order := binary.LittleEndian
+	setup := make([]byte, 12)
+	if _, err := io.ReadFull(conn, setup); err != nil {
+		return
+	}
+	nameLen, dataLen := int(order.Uint16(setup[6:])), int(order.Uint16(setup[8:]))
+	auth := make([]byte, pad4(nameLen)+pad4(dataLen))
+	if _, err := io.ReadFull(conn, auth); err != nil {
+		return
+	}
+	f.cookie = string(auth[pad4(nameLen) : pad4(nameLen)+dataLen])
+
+	// One screen with root window 0x123 and no allowed depths.
+	info := make([]byte, 32)
+	order.PutUint32(info[4:], 0x00200000)
+	order.PutUint32(info[8:], 0x001fffff)
+	order.PutUint16(info[16:], 4)
+	info[20] = 1
+	info = append(info, "fake"...)
+	screen := make([]byte, 40)
+	order.PutUint32(screen, 0x123)
+	info = append(info, screen...)
+	reply := []byte{1, 0, 11, 0, 0, 0}
+	reply = order.AppendUint16(reply, uint16(len(info)/4))
+	conn.Write(append(reply, info...))
+
+	atoms := map[string]uint32{}
+	var seq uint16
+	var message []byte
+	for {
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		body := make([]byte, int(order.Uint16(header[2:]))*4-4)
+		if _, err := io.ReadFull(conn, body); err != nil {
+			return
+		}
+		seq++
+		packet := make([]byte, 32)
+		order.PutUint16(packet[2:], seq)
+		switch header[0] {
+		case x11InternAtom:
+			name := string(body[4 : 4+order.Uint16(body)])
+			if atoms[name] == 0 {
+				atoms[name] = uint32(300 + len(atoms))
+			}
+			packet[0] = 1
+			order.PutUint32(packet[8:], atoms[name])
+			conn.Write(packet)
+		case x11ChangeProperty:
+			packet[0] = x11PropertyNotify
+			copy(packet[4:12], body[0:8])
+			order.PutUint32(packet[12:], f.time)
+			conn.Write(packet)
+		case x11SendEvent:
+			event := body[8:]
+			if order.Uint32(body) != 0x123 || event[0] != x11ClientMessage {
+				continue
+			}
+			if order.Uint32(event[8:]) == atoms["_NET_STARTUP_INFO_BEGIN"] {
+				message = nil
+			}
+			message = append(message, event[12:32]...)
+			if end := strings.IndexByte(string(message), 0); end >= 0 {
+				f.messages = append(f.messages, string(message[:end]))
+			}
+		case x11GetInputFocus:
+			packet[0] = 1
+			conn.Write(packet)
+		}
+	}
+}
+
+func TestActivationToken(t *testing.T) {
+	tests := []struct {
+		name      string
+		supported bool
+		// want is the token passed on by two launches in a row.
+		want [2]string
+	}{
+		{"compositor token", true, [2]string{"wl-token-1", "wl-token-2"}},
+		{"unsupported compositor", false, [2]string{"inherited", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := newSessionNotifier(func(key string) string {
+				return map[string]string{
+					"WAYLAND_DISPLAY":  "wayland-1",
+					"XDG_RUNTIME_DIR":  "/run/user/1000",
+					activationTokenEnv: "inherited",
+				}[key]
+			})
+			compositor := &fakeCompositor{supported: tt.supported}
+			n.dial = func(network, address string) (net.Conn, error) {
+				if address != "/run/user/1000/wayland-1" {
+					t.Errorf("dialed %s, want the compositor socket", address)
+				}
+				client, conn := net.Pipe()
+				go compositor.serve(conn)
+				return client, nil
+			}
+			for i, want := range tt.want {
+				var got string
+				for _, kv := range n.Env(Spec{Name: "org.example.Editor.desktop"}) {
+					got = strings.TrimPrefix(kv, activationTokenEnv+"=")
+				}
+				if got != want {
+					t.Errorf("launch %d: token = %q, want %q", i+1, got, want)
+				}
+			}
+			if tt.supported && compositor.appID != "org.example.Editor" {
+				t.Errorf("token requested for %q, want the desktop file ID", compositor.appID)
+			}
+		})
+	}
+}
+
+// fakeCompositor is a Wayland compositor implementing the requests
+// requestActivationToken sends.
+type fakeCompositor struct {
+	supported bool
+	appID     string
+	issued    int
+}
+
+func (f *fakeCompositor) serve(conn net.Conn) {
+	defer conn.Close()
+	send := func(object uint32, opcode uint16, args []byte) {
+		message := waylandUint(nil, object)
+		message = waylandUint(message, uint32(8+len(args))<<16|uint32(opcode))
+		conn.Write(append(message, args...))
+	}
+	var registry, activation, token uint32
+	for {
+		header := make([]byte, 8)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		word := waylandOrder.Uint32(header[4:])
+		args := make([]byte, word>>16-8)
+		if _, err := io.ReadFull(conn, args); err != nil {
+			return
+		}
+		object, opcode := waylandOrder.Uint32(header), uint16(word)
+		switch {
+		case object == waylandDisplayID && opcode == waylandGetRegistry:
+			registry = waylandOrder.Uint32(args)
+		case object == waylandDisplayID && opcode == waylandSync:
+			send(registry, waylandGlobal, waylandUint(waylandAppendString(waylandUint(nil, 1), "wl_compositor"), 6))
+			if f.supported {
+				send(registry, waylandGlobal, waylandUint(waylandAppendString(waylandUint(nil, 7), "xdg_activation_v1"), 1))
+			}
+			send(waylandOrder.Uint32(args), 0, waylandUint(nil, 0))
+		case object == registry && opcode == waylandBind:
+			if name, _ := waylandString(args[4:]); name == "xdg_activation_v1" {
+				activation = waylandOrder.Uint32(args[len(args)-4:])
+			}
+		case object == activation && opcode == activationGetToken:
+			token = waylandOrder.Uint32(args)
+		case object == token && opcode == tokenSetAppID:
+			f.appID, _ = waylandString(args)
+		case object == token && opcode == tokenCommit:
+			f.issued++
+			send(token, tokenDone, waylandAppendString(nil, "wl-token-"+strconv.Itoa(f.issued)))
+		}
+	}
+}
+
+type fakeNotifier struct {
+	specs []Spec
+}
+
+func (f *fakeNotifier) Env(spec Spec) []string {
+	f.specs = append(f.specs, spec)
+	return []string{startupIDEnv + "=fake-id"}
+}
+
+func TestStartUsesStartupNotifier(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	// The launcher's own startup variables belong to its window.
+	t.Setenv(activationTokenEnv, "launcher-token")
+	fake := &fakeNotifier{}
+	previous := SetStartupNotifier(fake)
+	defer SetStartupNotifier(previous)
+
+	proc, err := Start(Spec{
+		Name:          "startup-test",
+		Argv:          []string{"sh", "-c", `echo "id=$` + startupIDEnv + ` token=$` + activationTokenEnv + `"`},
+		StartupNotify: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-proc.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("process did not exit")
+	}
+	if len(fake.specs) != 1 || !fake.specs[0].StartupNotify {
+		t.Errorf("notifier saw %+v, want the launched spec", fake.specs)
+	}
+	log, err := os.ReadFile(proc.LogPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(log), "id=fake-id token=\n") {
+		t.Errorf("child environment = %q, want the fake ID and no inherited token", log)
+	}
+}
@@ -0,0 +1,192 @@
+package process
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+)
+
+// The Wayland client below speaks just enough of the wire protocol to ask
+// for an xdg_activation_v1 token. Messages use the host's byte order.
+
+var waylandOrder = binary.NativeEndian
+
+const (
+	waylandDisplayID = 1
+
+	// wl_display requests and events.
+	waylandSync        = 0
+	waylandGetRegistry = 1
+	waylandError       = 0
+
+	// wl_registry request and event.
+	waylandBind   = 0
+	waylandGlobal = 0
+
+	// xdg_activation_v1 requests.
+	activationDestroy  = 0
+	activationGetToken = 1
+
+	// xdg_activation_token_v1 requests and event.
+	tokenSetAppID = 1
+	tokenCommit   = 3
+	tokenDestroy  = 4
+	tokenDone     = 0
+)
+
+// waylandSocket returns the compositor socket WAYLAND_DISPLAY names, which
+// is relative to XDG_RUNTIME_DIR unless absolute.
+func waylandSocket(display, runtimeDir string) string {
+	if filepath.IsAbs(display) || runtimeDir == "" {
+		return display
+	}
+	return filepath.Join(runtimeDir, display)
+}
+
+type waylandConn struct {
+	conn   net.Conn
+	nextID uint32
+}
+
+type waylandMessage struct {
+	sender uint32
+	opcode uint16
+	args   []byte
+}
+
+func (c *waylandConn) newID() uint32 {
+	c.nextID++
+	return c.nextID
+}
+
+func (c *waylandConn) request(object uint32, opcode uint16, args []byte) error {
+	message := waylandOrder.AppendUint32(nil, object)
+	message = waylandOrder.AppendUint32(message, uint32(8+len(args))<<16|uint32(opcode))
+	_, err := c.conn.Write(append(message, args...))
+	return err
+}
+
+// read returns the next event, failing on a wl_display.error.
+func (c *waylandConn) read() (waylandMessage, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(c.conn, header); err != nil {
+		return waylandMessage{}, err
+	}
+	word := waylandOrder.Uint32(header[4:])
+	if word>>16 < 8 {
+		return waylandMessage{}, errors.New("invalid Wayland message")
+	}
+	msg := waylandMessage{
+		sender: waylandOrder.Uint32(header),
+		opcode: uint16(word),
+		args:   make([]byte, word>>16-8),
+	}
+	if _, err := io.ReadFull(c.conn, msg.args); err != nil {
+		return waylandMessage{}, err
+	}
+	if msg.sender == waylandDisplayID && msg.opcode == waylandError {
+		args := msg.args
+		if len(args) < 8 {
+			return waylandMessage{}, errors.New("Wayland protocol error")
+		}
+		code := waylandOrder.Uint32(args[4:])
+		text, _ := waylandString(args[8:])
+		return waylandMessage{}, fmt.Errorf("Wayland protocol error %d: %s", code, text)
+	}
+	return msg, nil
+}
+
+// requestActivationToken asks the compositor for an activation token for
+// appID.
+func requestActivationToken(conn net.Conn, appID string) (string, error) {
+	c := &waylandConn{conn: conn, nextID: waylandDisplayID}
+	registry := c.newID()
+	if err := c.request(waylandDisplayID, waylandGetRegistry, waylandUint(nil, registry)); err != nil {
+		return "", err
+	}
+	callback := c.newID()
+	if err := c.request(waylandDisplayID, waylandSync, waylandUint(nil, callback)); err != nil {
+		return "", err
+	}
+
+	// The globals are all announced before the sync callback fires.
+	var global uint32
+	found := false
+	for {
+		msg, err := c.read()
+		if err != nil {
+			return "", err
+		}
+		if msg.sender == callback {
+			break
+		}
+		if msg.sender == registry && msg.opcode == waylandGlobal && len(msg.args) >= 4 {
+			if name, _ := waylandString(msg.args[4:]); name == "xdg_activation_v1" {
+				global, found = waylandOrder.Uint32(msg.args), true
+			}
+		}
+	}
+	if !found {
+		return "", errors.New("compositor does not support xdg_activation_v1")
+	}
+
+	activation := c.newID()
+	args := waylandUint(nil, global)
+	args = waylandAppendString(args, "xdg_activation_v1")
+	args = waylandUint(args, 1)
+	args = waylandUint(args, activation)
+	if err := c.request(registry, waylandBind, args); err != nil {
+		return "", err
+	}
+	token := c.newID()
+	if err := c.request(activation, activationGetToken, waylandUint(nil, token)); err != nil {
+		return "", err
+	}
+	if appID != "" {
+		if err := c.request(token, tokenSetAppID, waylandAppendString(nil, appID)); err != nil {
+			return "", err
+		}
+	}
+	if err := c.request(token, tokenCommit, nil); err != nil {
+		return "", err
+	}
+	for {
+		msg, err := c.read()
+		if err != nil {
+			return "", err
+		}
+		if msg.sender == token && msg.opcode == tokenDone {
+			value, err := waylandString(msg.args)
+			if err != nil {
+				return "", err
+			}
+			c.request(token, tokenDestroy, nil)
+			c.request(activation, activationDestroy, nil)
+			return value, nil
+		}
+	}
+}
+
+func waylandUint(b []byte, v uint32) []byte {
+	return waylandOrder.AppendUint32(b, v)
+}
+
+// waylandAppendString appends s with its length, NUL terminator and padding.
+func waylandAppendString(b []byte, s string) []byte {
+	b = waylandOrder.AppendUint32(b, uint32(len(s)+1))
+	return appendPadded(b, append([]byte(s), 0))
+}
+
+func waylandString(args []byte) (string, error) {
+	if len(args) < 4 {
+		return "", errors.New("truncated Wayland string")
+	}
+	n := int(waylandOrder.Uint32(args))
+	if n == 0 || len(args) < 4+n {
+		return "", errors.New("truncated Wayland string")
+	}
+	return string(args[4 : 4+n-1]), nil
+}
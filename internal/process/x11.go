@@ -0,0 +1,347 @@
+package process
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// The X11 client below speaks just enough of the core protocol to broadcast
+// startup notification messages: the connection setup, InternAtom,
+// CreateWindow, ChangeProperty, SendEvent and GetInputFocus. Requests are
+// sent little-endian; the server swaps bytes as needed.
+
+var x11Order = binary.LittleEndian
+
+// X11 request opcodes.
+const (
+	x11CreateWindow   = 1
+	x11InternAtom     = 16
+	x11ChangeProperty = 18
+	x11SendEvent      = 25
+	x11GetInputFocus  = 43
+)
+
+const (
+	x11PropertyNotify = 28
+	x11ClientMessage  = 33
+
+	x11PropertyChangeMask = 0x00400000
+	x11CWEventMask        = 0x00000800
+	x11InputOnly          = 2
+	x11AtomString         = 31
+	x11PropModeAppend     = 2
+)
+
+// x11Display is where DISPLAY points.
+type x11Display struct {
+	network string
+	address string
+	// number is the display number, which selects the authorization cookie.
+	number string
+	screen int
+	// xauthority is the file holding authorization cookies.
+	xauthority string
+}
+
+// parseX11Display parses a DISPLAY value such as ":0", "unix:1.0",
+// "localhost:10.0" or the socket path XQuartz sets.
+func parseX11Display(display, xauthority string) (*x11Display, error) {
+	colon := strings.LastIndexByte(display, ':')
+	if colon < 0 {
+		return nil, fmt.Errorf("invalid DISPLAY %q", display)
+	}
+	host, rest := display[:colon], display[colon+1:]
+	number, screen, hasScreen := strings.Cut(rest, ".")
+	if _, err := strconv.Atoi(number); err != nil {
+		return nil, fmt.Errorf("invalid DISPLAY %q", display)
+	}
+	d := &x11Display{number: number, xauthority: xauthority}
+	if hasScreen {
+		n, err := strconv.Atoi(screen)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DISPLAY %q", display)
+		}
+		d.screen = n
+	}
+	switch {
+	case strings.HasPrefix(display, "/"):
+		// XQuartz names the socket after the whole value.
+		d.network, d.address = "unix", display[:colon+1+len(number)]
+	case host == "" || host == "unix":
+		d.network, d.address = "unix", "/tmp/.X11-unix/X"+number
+	default:
+		port, _ := strconv.Atoi(number)
+		d.network, d.address = "tcp", net.JoinHostPort(host, strconv.Itoa(6000+port))
+	}
+	return d, nil
+}
+
+// x11Conn is a connection to an X server.
+type x11Conn struct {
+	conn   net.Conn
+	root   uint32
+	nextID uint32
+}
+
+// connectX11 opens a connection to display with the MIT-MAGIC-COOKIE-1 from
+// the authority file, if there is one.
+func connectX11(conn net.Conn, display *x11Display, hostname string) (*x11Conn, error) {
+	name, data := readXauthority(display.xauthority, display.number, hostname, display.network == "tcp")
+	setup := []byte{'l', 0}
+	setup = x11Order.AppendUint16(setup, 11)
+	setup = x11Order.AppendUint16(setup, 0)
+	setup = x11Order.AppendUint16(setup, uint16(len(name)))
+	setup = x11Order.AppendUint16(setup, uint16(len(data)))
+	setup = append(setup, 0, 0)
+	setup = appendPadded(setup, []byte(name))
+	setup = appendPadded(setup, data)
+	if _, err := conn.Write(setup); err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, err
+	}
+	reply := make([]byte, int(x11Order.Uint16(header[6:]))*4)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return nil, err
+	}
+	switch header[0] {
+	case 0:
+		reason := reply[:min(int(header[1]), len(reply))]
+		return nil, fmt.Errorf("X server refused the connection: %s", bytes.TrimSpace(reason))
+	case 1:
+	default:
+		return nil, errors.New("X server requires further authentication")
+	}
+	if len(reply) < 32 {
+		return nil, errors.New("short X connection setup")
+	}
+	base, mask := x11Order.Uint32(reply[4:]), x11Order.Uint32(reply[8:])
+	vendor := int(x11Order.Uint16(reply[16:]))
+	screens, formats := int(reply[20]), int(reply[21])
+	offset := 32 + pad4(vendor) + 8*formats
+	for i := 0; i < screens; i++ {
+		if offset+40 > len(reply) {
+			break
+		}
+		if i == display.screen {
+			return &x11Conn{
+				conn:   conn,
+				root:   x11Order.Uint32(reply[offset:]),
+				nextID: base | mask&-mask,
+			}, nil
+		}
+		depths := int(reply[offset+39])
+		offset += 40
+		for j := 0; j < depths && offset+8 <= len(reply); j++ {
+			offset += 8 + 24*int(x11Order.Uint16(reply[offset+2:]))
+		}
+	}
+	return nil, fmt.Errorf("X screen %d does not exist", display.screen)
+}
+
+// request sends a request; body must be padded to a multiple of four bytes.
+func (c *x11Conn) request(opcode, data byte, body []byte) error {
+	message := []byte{opcode, data}
+	message = x11Order.AppendUint16(message, uint16(1+len(body)/4))
+	_, err := c.conn.Write(append(message, body...))
+	return err
+}
+
+// read returns the next reply, event or error from the server.
+func (c *x11Conn) read() ([]byte, error) {
+	packet := make([]byte, 32)
+	if _, err := io.ReadFull(c.conn, packet); err != nil {
+		return nil, err
+	}
+	switch packet[0] {
+	case 0:
+		return nil, fmt.Errorf("X error %d in request %d", packet[1], packet[10])
+	case 1:
+		extra := make([]byte, int(x11Order.Uint32(packet[4:]))*4)
+		if _, err := io.ReadFull(c.conn, extra); err != nil {
+			return nil, err
+		}
+		packet = append(packet, extra...)
+	}
+	return packet, nil
+}
+
+// reply waits for the reply to the last request, skipping events.
+func (c *x11Conn) reply() ([]byte, error) {
+	for {
+		packet, err := c.read()
+		if err != nil || packet[0] == 1 {
+			return packet, err
+		}
+	}
+}
+
+func (c *x11Conn) internAtom(name string) (uint32, error) {
+	body := x11Order.AppendUint16(nil, uint16(len(name)))
+	body = append(body, 0, 0)
+	body = appendPadded(body, []byte(name))
+	if err := c.request(x11InternAtom, 0, body); err != nil {
+		return 0, err
+	}
+	reply, err := c.reply()
+	if err != nil {
+		return 0, err
+	}
+	return x11Order.Uint32(reply[8:]), nil
+}
+
+// createWindow creates an unmapped window to send messages from and to
+// receive its own property changes.
+func (c *x11Conn) createWindow() (uint32, error) {
+	window := c.nextID
+	body := x11Order.AppendUint32(nil, window)
+	body = x11Order.AppendUint32(body, c.root)
+	body = append(body, 0, 0, 0, 0) // x, y
+	body = x11Order.AppendUint16(body, 1)
+	body = x11Order.AppendUint16(body, 1)
+	body = x11Order.AppendUint16(body, 0) // border
+	body = x11Order.AppendUint16(body, x11InputOnly)
+	body = x11Order.AppendUint32(body, 0) // visual: CopyFromParent
+	body = x11Order.AppendUint32(body, x11CWEventMask)
+	body = x11Order.AppendUint32(body, x11PropertyChangeMask)
+	return window, c.request(x11CreateWindow, 0, body)
+}
+
+// serverTime returns the server's current time by appending nothing to a
+// property of window and reading the time off the resulting PropertyNotify.
+func (c *x11Conn) serverTime(window, property uint32) (uint32, error) {
+	body := x11Order.AppendUint32(nil, window)
+	body = x11Order.AppendUint32(body, property)
+	body = x11Order.AppendUint32(body, x11AtomString)
+	body = append(body, 8, 0, 0, 0)
+	body = x11Order.AppendUint32(body, 0)
+	if err := c.request(x11ChangeProperty, x11PropModeAppend, body); err != nil {
+		return 0, err
+	}
+	for {
+		packet, err := c.read()
+		if err != nil {
+			return 0, err
+		}
+		if packet[0]&0x7f == x11PropertyNotify && x11Order.Uint32(packet[4:]) == window {
+			return x11Order.Uint32(packet[12:]), nil
+		}
+	}
+}
+
+// broadcast sends message to the root window as the startup notification
+// specification describes: NUL-terminated, in 20-byte ClientMessages whose
+// first has type begin and the rest type more.
+func (c *x11Conn) broadcast(window, begin, more uint32, message string) error {
+	data := append([]byte(message), 0)
+	messageType := begin
+	for len(data) > 0 {
+		chunk := make([]byte, 20)
+		data = data[copy(chunk, data):]
+
+		event := []byte{x11ClientMessage, 8, 0, 0}
+		event = x11Order.AppendUint32(event, window)
+		event = x11Order.AppendUint32(event, messageType)
+		event = append(event, chunk...)
+
+		body := x11Order.AppendUint32(nil, c.root)
+		body = x11Order.AppendUint32(body, x11PropertyChangeMask)
+		if err := c.request(x11SendEvent, 0, append(body, event...)); err != nil {
+			return err
+		}
+		messageType = more
+	}
+	return nil
+}
+
+// sync waits until the server has processed every request sent so far, so
+// errors surface before the connection is closed.
+func (c *x11Conn) sync() error {
+	if err := c.request(x11GetInputFocus, 0, nil); err != nil {
+		return err
+	}
+	_, err := c.reply()
+	return err
+}
+
+func (c *x11Conn) Close() error {
+	return c.conn.Close()
+}
+
+// readXauthority returns the MIT-MAGIC-COOKIE-1 for display number from the
+// authority file, or nothing if there is none; the server may still accept
+// the connection, for example through xhost. Local entries must name this
+// host; remote displays accept any entry for the number.
+func readXauthority(path, number, hostname string, remote bool) (string, []byte) {
+	const (
+		familyLocal = 256
+		familyWild  = 65535
+		cookie      = "MIT-MAGIC-COOKIE-1"
+	)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil
+	}
+	field := func() (string, bool) {
+		if len(data) < 2 {
+			return "", false
+		}
+		n := int(binary.BigEndian.Uint16(data))
+		if len(data) < 2+n {
+			return "", false
+		}
+		value := string(data[2 : 2+n])
+		data = data[2+n:]
+		return value, true
+	}
+	for len(data) >= 2 {
+		family := binary.BigEndian.Uint16(data)
+		data = data[2:]
+		address, ok1 := field()
+		entryNumber, ok2 := field()
+		name, ok3 := field()
+		secret, ok4 := field()
+		if !ok1 || !ok2 || !ok3 || !ok4 {
+			break
+		}
+		if name != cookie || (entryNumber != "" && entryNumber != number) {
+			continue
+		}
+		if remote || family == familyWild || (family == familyLocal && address == hostname) {
+			return name, []byte(secret)
+		}
+	}
+	return "", nil
+}
+
+// defaultXauthority returns the authority file Xlib would use.
+func defaultXauthority(getenv func(string) string) string {
+	if path := getenv("XAUTHORITY"); path != "" {
+		return path
+	}
+	if home := getenv("HOME"); home != "" {
+		return filepath.Join(home, ".Xauthority")
+	}
+	return ""
+}
+
+func pad4(n int) int {
+	return (n + 3) &^ 3
+}
+
+// appendPadded appends data followed by zeros up to a multiple of four bytes.
+func appendPadded(b, data []byte) []byte {
+	b = append(b, data...)
+	return append(b, make([]byte, pad4(len(data))-len(data))...)
+}
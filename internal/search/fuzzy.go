@@ -3,130 +3,180 @@ package search
 import (
 	"sort"
 	"strings"
-
-	"github.com/SagenKoder/launcher/internal/applications"
 )
 
-// Filter returns the subset of applications that match the query using a simple
-// fuzzy subsequence match. Results are ordered by match quality and name.
-func Filter(apps []applications.Application, query string) []applications.Application {
-	trimmed := strings.TrimSpace(query)
-	if trimmed == "" {
+// Result is an item matching a query. Positions holds the indexes of the
+// runes of its title that matched, or nil when another field matched.
+type Result struct {
+	Item      Item
+	Positions []int
+}
+
+// Search returns the items matching every term of the query (see Query),
+// ordered by match quality and title, with commands after every other kind.
+func Search(items []Item, query string) []Result {
+	parsed := ParseQuery(query)
+	if parsed.Empty() {
 		return nil
 	}
 
-	q := strings.ToLower(trimmed)
-	results := scoreApplications(apps, q)
-	if len(results) == 0 {
+	scored := scoreItems(items, parsed, strings.ToLower(strings.TrimSpace(query)))
+	if len(scored) == 0 {
 		return nil
 	}
 
-	filtered := make([]applications.Application, len(results))
-	for i, res := range results {
-		filtered[i] = res.app
+	results := make([]Result, len(scored))
+	for i, res := range scored {
+		results[i] = Result{Item: res.item, Positions: res.positions}
 	}
-	return filtered
+	return results
 }
 
-type scoredApp struct {
-	app   applications.Application
-	score int
-	kind  string
+// Boost returns extra ranking points for item when searching for query, on
+// top of how well it matched.
+type Boost func(item Item, query string) int
+
+var boost Boost
+
+// SetBoost installs fn to adjust the ranking of matching items, such as by
+// how often they were launched. nil removes it.
+func SetBoost(fn Boost) {
+	boost = fn
 }
 
-func scoreApplications(apps []applications.Application, q string) []scoredApp {
-	results := make([]scoredApp, 0, 32)
-	for _, app := range apps {
-		if score, kind := scoreApplication(app, q); score > 0 {
-			results = append(results, scoredApp{app: app, score: score, kind: kind})
+type scoredItem struct {
+	item      Item
+	score     int
+	positions []int
+}
+
+func scoreItems(items []Item, query Query, q string) []scoredItem {
+	results := make([]scoredItem, 0, 32)
+	for _, item := range items {
+		if score, _, positions, ok := query.matchItem(item); ok {
+			if boost != nil {
+				score += boost(item, q)
+			}
+			results = append(results, scoredItem{item: item, score: score, positions: positions})
 		}
 	}
 
 	sort.Slice(results, func(i, j int) bool {
+		// Commands only fill in after every other matching item.
+		if ci, cj := results[i].item.Kind == KindCommand, results[j].item.Kind == KindCommand; ci != cj {
+			return cj
+		}
 		if results[i].score == results[j].score {
-			nameI := strings.ToLower(results[i].app.Name)
-			nameJ := strings.ToLower(results[j].app.Name)
-			if nameI == nameJ {
-				return results[i].app.Name < results[j].app.Name
+			titleI := strings.ToLower(results[i].item.Title)
+			titleJ := strings.ToLower(results[j].item.Title)
+			if titleI == titleJ {
+				return results[i].item.Title < results[j].item.Title
 			}
-			return nameI < nameJ
+			return titleI < titleJ
 		}
 		return results[i].score > results[j].score
 	})
 	return results
 }
 
-func scoreApplication(app applications.Application, q string) (int, string) {
-	lowerName := strings.ToLower(app.Name)
-	lowerExec := strings.ToLower(app.Exec)
+// Per-field base scores. Every field ranks below a substring match on the
+// item's title so metadata only decides between weaker candidates. How well
+// a field matched only moves its score by less than tierGap/2 either way
+// (see squeeze), so a match in a better field always ranks higher.
+const (
+	nameSubstringWeight     = 2000
+	keywordSubstringWeight  = 1700
+	genericSubstringWeight  = 1600
+	execSubstringWeight     = 1500
+	categorySubstringWeight = 1300
+	commentSubstringWeight  = 1200
+	nameFuzzyWeight         = 1000
+	execFuzzyWeight         = 800
+
+	// tierGap is the smallest difference between two field weights.
+	tierGap = 100
+)
+
+func scoreItem(item Item, q string) (int, string) {
+	names := []string{item.Title}
+	for _, alias := range item.Aliases {
+		if alias != "" && !strings.EqualFold(alias, item.Title) {
+			names = append(names, alias)
+		}
+	}
 
-	if idx := strings.Index(lowerName, q); idx >= 0 {
-		score := 2000 - idx*20 - len(lowerName)
-		return score, "name-substring"
+	substringFields := []struct {
+		kind   string
+		weight int
+		values []string
+	}{
+		{"name-substring", nameSubstringWeight, names},
+		{"keyword-substring", keywordSubstringWeight, item.Keywords},
+		{"generic-substring", genericSubstringWeight, []string{item.Subtitle}},
+		{"exec-substring", execSubstringWeight, []string{item.Command}},
+		{"category-substring", categorySubstringWeight, item.Categories},
+		{"comment-substring", commentSubstringWeight, []string{item.Description}},
+	}
+	best, bestKind := 0, ""
+	for _, field := range substringFields {
+		if score, ok := substringScore(q, field.values); ok && field.weight+squeeze(score) > best {
+			best, bestKind = field.weight+squeeze(score), field.kind
+		}
 	}
-	if idx := strings.Index(lowerExec, q); idx >= 0 {
-		score := 1500 - idx*20 - len(lowerExec)
-		return score, "exec-substring"
+	if bestKind != "" {
+		return best, bestKind
 	}
 
-	if ok, score := fuzzyScore(q, lowerName); ok {
-		return 1000 + score, "name-fuzzy"
+	for _, name := range names {
+		if score, _, ok := Match(q, name); ok && nameFuzzyWeight+squeeze(score) > best {
+			best, bestKind = nameFuzzyWeight+squeeze(score), "name-fuzzy"
+		}
 	}
-	if ok, score := fuzzyScore(q, lowerExec); ok {
-		return 800 + score, "exec-fuzzy"
+	if bestKind != "" {
+		return best, bestKind
+	}
+	if score, _, ok := Match(q, item.Command); ok {
+		return execFuzzyWeight + squeeze(score), "exec-fuzzy"
 	}
 	return 0, ""
 }
 
-func fuzzyScore(query, candidate string) (bool, int) {
-	qr := []rune(query)
-	cr := []rune(candidate)
-	if len(qr) == 0 || len(cr) == 0 {
-		return false, 0
-	}
-
-	qi := 0
-	score := 0
-	lastMatchIndex := -1
-
-	for ci, r := range cr {
-		if qi >= len(qr) {
-			break
+// substringScore returns the best score for q appearing in any of values,
+// preferring early and short matches.
+func substringScore(q string, values []string) (int, bool) {
+	best, found := 0, false
+	for _, value := range values {
+		lower := strings.ToLower(value)
+		idx := strings.Index(lower, q)
+		if idx < 0 {
+			continue
 		}
-		if r == qr[qi] {
-			score += 5
-			if lastMatchIndex == ci-1 {
-				score += 10 // reward consecutive matches
-			}
-			if qi == 0 {
-				score += max(15-ci*2, 0) // prefer matches near the start
-			}
-			lastMatchIndex = ci
-			qi++
+		score := -idx*20 - len(lower)
+		if !found || score > best {
+			best, found = score, true
 		}
 	}
-
-	if qi != len(qr) {
-		return false, 0
-	}
-
-	return true, score - len(cr)
+	return best, found
 }
 
-func max(a, b int) int {
-	if a > b {
-		return a
+// squeeze maps a score within one field onto (-tierGap/2, tierGap/2),
+// keeping its order: the larger the score, the closer it gets to the bound.
+func squeeze(score int) int {
+	const scale = 100
+	magnitude := score
+	if magnitude < 0 {
+		magnitude = -magnitude
 	}
-	return b
+	return (tierGap/2 - 1) * score / (magnitude + scale)
 }
 
-// DebugScore exposes how an application scored for a given query.
+// DebugScore exposes how an item scored for a given query, with the kind of
+// field each text term matched joined by "+".
 // Intended for diagnostics only.
-func DebugScore(app applications.Application, query string) (string, int) {
-	trimmed := strings.TrimSpace(query)
-	if trimmed == "" {
+func DebugScore(item Item, query string) (string, int) {
+	score, kinds, _, ok := ParseQuery(query).matchItem(item)
+	if !ok {
 		return "", 0
 	}
-	score, kind := scoreApplication(app, strings.ToLower(trimmed))
-	return kind, score
+	return strings.Join(kinds, "+"), score
 }
@@ -0,0 +1,79 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSearchFieldTiers(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		items []Item
+		want  []string
+	}{
+		{
+			name:  "late name match over early keyword match",
+			query: "code",
+			items: []Item{
+				{ID: "geany", Title: "Geany", Keywords: []string{"code"}},
+				{ID: "vscode", Title: "Microsoft Visual Studio Code"},
+			},
+			want: []string{"vscode", "geany"},
+		},
+		{
+			name:  "long keyword over short generic name",
+			query: "edit",
+			items: []Item{
+				{ID: "generic", Title: "Gedit", Subtitle: "Edit"},
+				{ID: "keyword", Title: "Kate", Keywords: []string{strings.Repeat("x", 200) + "edit"}},
+			},
+			want: []string{"generic", "keyword"},
+		},
+		{
+			name:  "comment over fuzzy name",
+			query: "term",
+			items: []Item{
+				{ID: "fuzzy", Title: "The Extended Remote Manager"},
+				{ID: "comment", Title: "Shell", Description: "Use the command line from a term window"},
+			},
+			want: []string{"comment", "fuzzy"},
+		},
+		{
+			name:  "better match within a field",
+			query: "code",
+			items: []Item{
+				{ID: "late", Title: "Visual Studio Code"},
+				{ID: "early", Title: "Code::Blocks"},
+				{ID: "exact", Title: "Code"},
+			},
+			want: []string{"exact", "early", "late"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, result := range Search(tt.items, tt.query) {
+				got = append(got, result.Item.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSqueezeKeepsOrderWithinTier(t *testing.T) {
+	previous := squeeze(-100000)
+	if previous <= -tierGap/2 {
+		t.Errorf("squeeze(-100000) = %d, want above %d", previous, -tierGap/2)
+	}
+	for _, score := range []int{-1000, -100, -10, -1, 0, 1, 10, 100, 1000, 100000} {
+		got := squeeze(score)
+		if got < previous || got >= tierGap/2 {
+			t.Errorf("squeeze(%d) = %d, want in [%d, %d)", score, got, previous, tierGap/2)
+		}
+		previous = got
+	}
+}
@@ -0,0 +1,52 @@
+package search
+
+// Kind says what activating an Item does.
+type Kind string
+
+const (
+	// KindApplication starts an installed application.
+	KindApplication Kind = "application"
+	// KindAction starts an application through one of its desktop actions.
+	KindAction Kind = "action"
+	// KindCommand runs an executable from $PATH or a typed command line.
+	// Commands rank after every other kind.
+	KindCommand Kind = "command"
+	// KindPlugin opens a plugin.
+	KindPlugin Kind = "plugin"
+)
+
+// Item is anything the launcher can search for and activate. Sources such as
+// application discovery and the plugin registry produce Items; the ranker
+// and the result list only consume them. Value carries the source's own
+// object, which whoever activates the item switches on.
+type Item struct {
+	// ID identifies the item across runs, for example in launch history.
+	ID   string
+	Kind Kind
+	// Title is the name shown in the list and the main field matched.
+	Title string
+	// Subtitle describes the item, such as an application's generic name.
+	Subtitle string
+	// Detail is a short label shown at the trailing edge of the row, such as
+	// the packaging format.
+	Detail string
+	// Source is where the item came from, such as "flatpak" or "plugin",
+	// for the src: filter.
+	Source   string
+	Keywords []string
+	// IconPath is an icon file, or a theme icon name that is resolved when
+	// the icon is loaded.
+	IconPath string
+	// Actions are alternative ways to activate the item, offered in its
+	// context menu.
+	Actions []Item
+
+	// The remaining fields are matched but not shown. Aliases are other
+	// names matched like the title, such as an untranslated name.
+	Aliases     []string
+	Categories  []string
+	Description string
+	Command     string
+
+	Value any
+}
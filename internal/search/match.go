@@ -0,0 +1,287 @@
+package search
+
+import (
+	"unicode"
+)
+
+// Scoring constants, following fzf. A matched rune is worth scoreMatch plus a
+// bonus depending on where it sits in the text; gaps between matched runes
+// cost scoreGapStart for the first skipped rune and scoreGapExtension for each
+// further one.
+const (
+	scoreMatch        = 16
+	scoreGapStart     = -3
+	scoreGapExtension = -1
+
+	// bonusBoundary rewards a match at the start of a word.
+	bonusBoundary = scoreMatch / 2
+	// bonusBoundaryWhite and bonusBoundaryDelimiter reward word starts after
+	// whitespace and after path separators or punctuation, which are the
+	// most deliberate boundaries.
+	bonusBoundaryWhite     = bonusBoundary + 2
+	bonusBoundaryDelimiter = bonusBoundary + 1
+	// bonusNonWord rewards matching punctuation itself.
+	bonusNonWord = scoreMatch / 2
+	// bonusCamel123 rewards camelCase humps and letter-digit transitions.
+	bonusCamel123 = bonusBoundary + scoreGapExtension
+	// bonusConsecutive is the minimum bonus of a rune matched right after
+	// the previous one, so runs are not broken up for a slightly better bonus
+	// elsewhere.
+	bonusConsecutive = -(scoreGapStart + scoreGapExtension)
+	// bonusFirstCharMultiplier weighs the bonus of the first query rune more,
+	// since it decides where the match starts.
+	bonusFirstCharMultiplier = 2
+
+	// maxMatchCells bounds the size of the scoring matrix. Longer texts fall
+	// back to the greedy alignment.
+	maxMatchCells = 64 * 1024
+)
+
+type charClass int
+
+const (
+	charWhite charClass = iota
+	charNonWord
+	charDelimiter
+	charLower
+	charUpper
+	charLetter
+	charNumber
+)
+
+func classOf(r rune) charClass {
+	switch {
+	case r >= 'a' && r <= 'z':
+		return charLower
+	case r >= 'A' && r <= 'Z':
+		return charUpper
+	case r >= '0' && r <= '9':
+		return charNumber
+	case unicode.IsSpace(r):
+		return charWhite
+	case r == '/' || r == '\\' || r == ',' || r == ':' || r == ';' || r == '|':
+		return charDelimiter
+	case unicode.IsLower(r):
+		return charLower
+	case unicode.IsUpper(r):
+		return charUpper
+	case unicode.IsLetter(r):
+		return charLetter
+	case unicode.IsNumber(r):
+		return charNumber
+	}
+	return charNonWord
+}
+
+// bonusAt returns the bonus for matching a rune of class current that follows
+// a rune of class previous.
+func bonusAt(previous, current charClass) int {
+	if current > charNonWord {
+		switch previous {
+		case charWhite:
+			return bonusBoundaryWhite
+		case charDelimiter:
+			return bonusBoundaryDelimiter
+		case charNonWord:
+			return bonusBoundary
+		}
+	}
+	if previous == charLower && current == charUpper ||
+		previous != charNumber && current == charNumber {
+		return bonusCamel123
+	}
+	switch current {
+	case charNonWord, charDelimiter:
+		return bonusNonWord
+	case charWhite:
+		return bonusBoundaryWhite
+	}
+	return 0
+}
+
+// Match scores how well query matches text as a case-insensitive
+// subsequence. It finds the alignment with the highest score rather than the
+// first one, so "code" matches the last word of "Visual Studio Code" and
+// "vsc" its initials. positions holds the indexes of the matched runes in
+// text. ok is false when query is not a subsequence of text.
+func Match(query, text string) (score int, positions []int, ok bool) {
+	pattern := foldRunes([]rune(query))
+	original := []rune(text)
+	if len(pattern) == 0 || len(original) < len(pattern) {
+		return 0, nil, false
+	}
+	folded := foldRunes(original)
+
+	// Only the part of text between the first possible match of the first
+	// rune and the last possible match of the last rune matters.
+	first, last := subsequenceBounds(pattern, folded)
+	if first < 0 {
+		return 0, nil, false
+	}
+	if len(pattern)*(last-first+1) > maxMatchCells {
+		return greedyMatch(pattern, original, folded)
+	}
+
+	bonus := make([]int, len(folded))
+	previous := charWhite
+	if first > 0 {
+		previous = classOf(original[first-1])
+	}
+	for j := first; j <= last; j++ {
+		current := classOf(original[j])
+		bonus[j] = bonusAt(previous, current)
+		previous = current
+	}
+
+	score, positions = alignMatch(pattern, folded, bonus, first, last)
+	return score, positions, true
+}
+
+// alignMatch fills the scoring matrices for pattern against text[first:last+1]
+// and backtracks from the best cell.
+func alignMatch(pattern, text []rune, bonus []int, first, last int) (int, []int) {
+	const unset = -1 << 30
+	width := last - first + 1
+	cells := len(pattern) * width
+	// match holds the best score with pattern[i] matched at text[j];
+	// carry the best score with pattern[i] matched at or before j, gap
+	// penalties included, and carryFrom the column of that match.
+	match := make([]int, cells)
+	chunk := make([]int, cells) // bonus of the first rune of the current run
+	consecutive := make([]bool, cells)
+	carry := make([]int, cells)
+	carryFrom := make([]int, cells)
+	carryGap := make([]bool, cells)
+
+	for i, pr := range pattern {
+		row := i * width
+		for c := 0; c < width; c++ {
+			j := first + c
+			cell := row + c
+			match[cell] = unset
+			if c >= i && text[j] == pr {
+				if i == 0 {
+					match[cell] = scoreMatch + bonus[j]*bonusFirstCharMultiplier
+					chunk[cell] = bonus[j]
+				} else if c > 0 {
+					up := cell - width - 1
+					if match[up] != unset {
+						b := max(bonus[j], chunk[up], bonusConsecutive)
+						match[cell] = match[up] + scoreMatch + b
+						chunk[cell] = chunk[up]
+						consecutive[cell] = true
+					}
+					if carry[up] != unset && carry[up]+scoreMatch+bonus[j] > match[cell] {
+						match[cell] = carry[up] + scoreMatch + bonus[j]
+						chunk[cell] = bonus[j]
+						consecutive[cell] = false
+					}
+				}
+			}
+
+			carry[cell], carryFrom[cell], carryGap[cell] = match[cell], j, false
+			if c > 0 && carry[cell-1] != unset {
+				penalty := scoreGapStart
+				if carryGap[cell-1] {
+					penalty = scoreGapExtension
+				}
+				if extended := carry[cell-1] + penalty; extended > carry[cell] {
+					carry[cell], carryFrom[cell], carryGap[cell] = extended, carryFrom[cell-1], true
+				}
+			}
+		}
+	}
+
+	// Trailing text after the last match is not penalized.
+	row := (len(pattern) - 1) * width
+	best, bestCol := unset, -1
+	for c := 0; c < width; c++ {
+		if match[row+c] > best {
+			best, bestCol = match[row+c], c
+		}
+	}
+
+	positions := make([]int, len(pattern))
+	c := bestCol
+	for i := len(pattern) - 1; i >= 0; i-- {
+		positions[i] = first + c
+		if i == 0 {
+			break
+		}
+		cell := i*width + c
+		if consecutive[cell] {
+			c--
+		} else {
+			c = carryFrom[cell-width-1] - first
+		}
+	}
+	return best, positions
+}
+
+// greedyMatch scores the leftmost alignment of pattern. It is only used for
+// texts too long for alignMatch.
+func greedyMatch(pattern, original, folded []rune) (int, []int, bool) {
+	positions := make([]int, 0, len(pattern))
+	score, previous := 0, charWhite
+	for j, r := range folded {
+		current := classOf(original[j])
+		if len(positions) < len(pattern) && r == pattern[len(positions)] {
+			b := bonusAt(previous, current)
+			switch {
+			case len(positions) == 0:
+				b *= bonusFirstCharMultiplier
+			case positions[len(positions)-1] == j-1:
+				b = max(b, bonusConsecutive)
+			default:
+				score += scoreGapStart + scoreGapExtension*(j-positions[len(positions)-1]-2)
+			}
+			score += scoreMatch + b
+			positions = append(positions, j)
+		}
+		previous = current
+	}
+	if len(positions) < len(pattern) {
+		return 0, nil, false
+	}
+	return score, positions, true
+}
+
+// subsequenceBounds returns the first index pattern can start matching at
+// and the last index it can end at, or -1 when it is not a subsequence.
+func subsequenceBounds(pattern, text []rune) (int, int) {
+	first, i := -1, 0
+	for j, r := range text {
+		if r == pattern[i] {
+			if i == 0 {
+				first = j
+			}
+			if i++; i == len(pattern) {
+				break
+			}
+		}
+	}
+	if i < len(pattern) {
+		return -1, -1
+	}
+	last := -1
+	i = len(pattern) - 1
+	for j := len(text) - 1; j >= 0; j-- {
+		if text[j] == pattern[i] {
+			if i == len(pattern)-1 {
+				last = j
+			}
+			if i--; i < 0 {
+				break
+			}
+		}
+	}
+	return first, last
+}
+
+func foldRunes(runes []rune) []rune {
+	folded := make([]rune, len(runes))
+	for i, r := range runes {
+		folded[i] = unicode.ToLower(r)
+	}
+	return folded
+}
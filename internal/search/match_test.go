@@ -0,0 +1,88 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		text      string
+		positions []int
+		ok        bool
+	}{
+		{"last word", "code", "Visual Studio Code", []int{14, 15, 16, 17}, true},
+		{"initials", "vsc", "Visual Studio Code", []int{0, 7, 14}, true},
+		{"case insensitive", "VSC", "visual studio code", []int{0, 7, 14}, true},
+		{"camel case", "fb", "fooBar", []int{0, 3}, true},
+		{"camel case hump", "ob", "openBrowser", []int{0, 4}, true},
+		{"path separators", "ubf", "/usr/bin/firefox", []int{1, 5, 9}, true},
+		{"non-ASCII folding", "ÉCL", "éclair", []int{0, 1, 2}, true},
+		{"non-ASCII text", "zur", "Größe zurück", []int{6, 7, 8}, true},
+		{"not a subsequence", "xyz", "Firefox", nil, false},
+		{"out of order", "oc", "Code", nil, false},
+		{"query longer than text", "firefox", "fire", nil, false},
+		{"empty query", "", "Firefox", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, positions, ok := Match(tt.query, tt.text)
+			if ok != tt.ok || !reflect.DeepEqual(positions, tt.positions) {
+				t.Errorf("Match(%q, %q) = %v, %v; want %v, %v", tt.query, tt.text, positions, ok, tt.positions, tt.ok)
+			}
+		})
+	}
+}
+
+func TestMatchPrefersBetterAlignment(t *testing.T) {
+	tests := []struct {
+		query, better, worse string
+	}{
+		{"code", "Code", "Decoder"},
+		{"term", "Terminal", "Thermometer"},
+		{"vsc", "Visual Studio Code", "Visualscope"},
+	}
+	for _, tt := range tests {
+		better, _, ok := Match(tt.query, tt.better)
+		if !ok {
+			t.Fatalf("Match(%q, %q) did not match", tt.query, tt.better)
+		}
+		worse, _, ok := Match(tt.query, tt.worse)
+		if !ok {
+			t.Fatalf("Match(%q, %q) did not match", tt.query, tt.worse)
+		}
+		if better <= worse {
+			t.Errorf("Match(%q): %q scored %d, not above %q with %d", tt.query, tt.better, better, tt.worse, worse)
+		}
+	}
+}
+
+func TestMatchLongTextFallsBackToGreedy(t *testing.T) {
+	text := strings.Repeat("ab ", maxMatchCells) + "c"
+	_, positions, ok := Match("abc", text)
+	if !ok {
+		t.Fatal("Match did not match the long text")
+	}
+	if want := []int{0, 1, len([]rune(text)) - 1}; !reflect.DeepEqual(positions, want) {
+		t.Errorf("positions = %v, want %v", positions, want)
+	}
+}
+
+func BenchmarkMatch(b *testing.B) {
+	benchmarks := []struct {
+		name, query, text string
+	}{
+		{"short", "vsc", "Visual Studio Code"},
+		{"long", "abc", strings.Repeat("ab ", maxMatchCells) + "c"},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				Match(bm.query, bm.text)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Query is a parsed search. Every term must hold for an item to match, in
+// any order:
+//
+//	code vis          "code" and "vis" both match some field
+//	"visual studio"   quotes keep spaces inside one term
+//	!beta             no field contains "beta"
+//	kind:plugin       Kind starts with "plugin"
+//	cat:development   a category starts with "development"
+//	src:flatpak       Source starts with "flatpak"
+//
+// Filters may be negated as well, as in !src:snap. A prefix that is not a
+// known field, such as "c:", is searched as text.
+type Query struct {
+	Terms []Term
+}
+
+// Term is one condition of a Query.
+type Term struct {
+	// Field is the filtered field, or empty for text matched against every
+	// field.
+	Field Field
+	// Text is the lowercased value.
+	Text   string
+	Negate bool
+}
+
+// Field names what a filter term compares against.
+type Field string
+
+const (
+	FieldKind     Field = "kind"
+	FieldCategory Field = "cat"
+	FieldSource   Field = "src"
+)
+
+var fieldNames = map[string]Field{
+	"kind":     FieldKind,
+	"cat":      FieldCategory,
+	"category": FieldCategory,
+	"src":      FieldSource,
+	"source":   FieldSource,
+}
+
+// ParseQuery splits text into terms at unquoted whitespace.
+func ParseQuery(text string) Query {
+	var query Query
+	for _, token := range tokenize(text) {
+		var term Term
+		if !token.quoted {
+			// A bare "!" or "kind:" is still being typed and is dropped
+			// rather than searched for literally.
+			if rest, ok := strings.CutPrefix(token.text, "!"); ok {
+				if rest == "" {
+					continue
+				}
+				term.Negate = true
+				token.text = rest
+			}
+			if name, value, ok := strings.Cut(token.text, ":"); ok {
+				if field, known := fieldNames[strings.ToLower(name)]; known {
+					if value == "" {
+						continue
+					}
+					term.Field = field
+					token.text = value
+				}
+			}
+		}
+		term.Text = strings.ToLower(token.text)
+		query.Terms = append(query.Terms, term)
+	}
+	return query
+}
+
+// Empty reports whether the query has no terms.
+func (q Query) Empty() bool {
+	return len(q.Terms) == 0
+}
+
+type token struct {
+	text string
+	// quoted tokens are searched literally, without negation or fields.
+	quoted bool
+}
+
+// tokenize splits text at whitespace outside double quotes. An unterminated
+// quote runs to the end of text.
+func tokenize(text string) []token {
+	var tokens []token
+	var current strings.Builder
+	inQuote, quoted := false, false
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, token{text: current.String(), quoted: quoted})
+		}
+		current.Reset()
+		quoted = false
+	}
+	for _, r := range text {
+		switch {
+		case r == '"':
+			if inQuote {
+				flush()
+			} else if current.Len() == 0 {
+				quoted = true
+			} else {
+				current.WriteRune(r)
+				continue
+			}
+			inQuote = !inQuote
+		case unicode.IsSpace(r) && !inQuote:
+			flush()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+	return tokens
+}
+
+// matchItem scores item against the query: the sum of the scores of its
+// text terms, or 1 when there are only filters. positions holds the title
+// runes matched by text terms that matched a name.
+func (q Query) matchItem(item Item) (score int, kinds []string, positions []int, ok bool) {
+	for _, term := range q.Terms {
+		switch {
+		case term.Field != "":
+			if term.matchesField(item) == term.Negate {
+				return 0, nil, nil, false
+			}
+		case term.Negate:
+			if containsText(item, term.Text) {
+				return 0, nil, nil, false
+			}
+		default:
+			termScore, kind := scoreItem(item, term.Text)
+			if termScore <= 0 {
+				return 0, nil, nil, false
+			}
+			score += termScore
+			kinds = append(kinds, kind)
+			if strings.HasPrefix(kind, "name-") {
+				// Nothing is highlighted when an alias matched instead of the
+				// displayed title.
+				if _, matched, ok := Match(term.Text, item.Title); ok {
+					positions = mergePositions(positions, matched)
+				}
+			}
+		}
+	}
+	if len(kinds) == 0 {
+		score = 1
+	}
+	return score, kinds, positions, true
+}
+
+func (t Term) matchesField(item Item) bool {
+	switch t.Field {
+	case FieldKind:
+		return hasFold(string(item.Kind), t.Text)
+	case FieldCategory:
+		for _, category := range item.Categories {
+			if hasFold(category, t.Text) {
+				return true
+			}
+		}
+		return false
+	case FieldSource:
+		return hasFold(item.Source, t.Text)
+	}
+	return false
+}
+
+// hasFold reports whether value starts with the lowercase prefix, ignoring
+// case, so kind:app and cat:dev need not be spelled out.
+func hasFold(value, prefix string) bool {
+	return strings.HasPrefix(strings.ToLower(value), prefix)
+}
+
+// containsText reports whether any matched field contains q as a substring.
+// Negated terms exclude literal occurrences only; a fuzzy match would exclude
+// far more than intended.
+func containsText(item Item, q string) bool {
+	fields := [][]string{
+		{item.Title, item.Subtitle, item.Command, item.Description},
+		item.Aliases,
+		item.Keywords,
+		item.Categories,
+	}
+	for _, values := range fields {
+		for _, value := range values {
+			if strings.Contains(strings.ToLower(value), q) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mergePositions returns the sorted union of two sorted index lists.
+func mergePositions(a, b []int) []int {
+	merged := make([]int, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case j == len(b) || (i < len(a) && a[i] < b[j]):
+			merged = append(merged, a[i])
+			i++
+		case i == len(a) || b[j] < a[i]:
+			merged = append(merged, b[j])
+			j++
+		default:
+			merged = append(merged, a[i])
+			i++
+			j++
+		}
+	}
+	return merged
+}
@@ -0,0 +1,89 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		text string
+		want []Term
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"Code  VIS", []Term{{Text: "code"}, {Text: "vis"}}},
+		{`"visual studio" code`, []Term{{Text: "visual studio"}, {Text: "code"}}},
+		{`"unterminated phrase`, []Term{{Text: "unterminated phrase"}}},
+		{`a"b c`, []Term{{Text: `a"b`}, {Text: "c"}}},
+		{`""`, nil},
+		{"!beta", []Term{{Text: "beta", Negate: true}}},
+		{`"!beta"`, []Term{{Text: "!beta"}}},
+		{"kind:Plugin", []Term{{Field: FieldKind, Text: "plugin"}}},
+		{"category:dev cat:ide", []Term{{Field: FieldCategory, Text: "dev"}, {Field: FieldCategory, Text: "ide"}}},
+		{"!src:snap source:flatpak", []Term{{Field: FieldSource, Text: "snap", Negate: true}, {Field: FieldSource, Text: "flatpak"}}},
+		{`"kind:plugin"`, []Term{{Text: "kind:plugin"}}},
+		{"c:foo", []Term{{Text: "c:foo"}}},
+		{"!c:foo", []Term{{Text: "c:foo", Negate: true}}},
+		{"code !", []Term{{Text: "code"}}},
+		{"code kind: !src:", []Term{{Text: "code"}}},
+		{"c:", []Term{{Text: "c:"}}},
+		{"!!", []Term{{Text: "!", Negate: true}}},
+	}
+	for _, tt := range tests {
+		if got := ParseQuery(tt.text).Terms; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseQuery(%q) = %+v, want %+v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSearchQuery(t *testing.T) {
+	items := []Item{
+		{ID: "code", Title: "Visual Studio Code", Kind: KindApplication, Source: "desktop", Categories: []string{"Development", "IDE"}},
+		{ID: "code-beta", Title: "Visual Studio Code Beta", Kind: KindApplication, Source: "snap", Categories: []string{"Development"}},
+		{ID: "calc", Title: "Calculator", Kind: KindApplication, Source: "flatpak", Categories: []string{"Utility"}, Keywords: []string{"math"}},
+		{ID: "chat", Title: "Chat", Kind: KindPlugin, Source: "plugin"},
+		{ID: "code-cmd", Title: "code", Kind: KindCommand, Source: "path", Command: "code"},
+	}
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"code vis", []string{"code", "code-beta"}},
+		{"studio visual", []string{"code", "code-beta"}},
+		{"visual studio code", []string{"code", "code-beta"}},
+		{`"studio visual"`, nil},
+		{"code zzz", nil},
+		{"code !beta", []string{"code", "code-cmd"}},
+		{"code !", []string{"code", "code-beta", "code-cmd"}},
+		{"!", nil},
+		{"kind:plugin", []string{"chat"}},
+		{"kind:app code", []string{"code", "code-beta"}},
+		{"cat:dev", []string{"code", "code-beta"}},
+		{"CAT:Development !src:snap", []string{"code"}},
+		{"src:flat", []string{"calc"}},
+		{"!kind:app", []string{"chat", "code-cmd"}},
+		{"kind:command code", []string{"code-cmd"}},
+		{"math kind:app", []string{"calc"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, result := range Search(items, tt.query) {
+			got = append(got, result.Item.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSearchHighlightsEveryTerm(t *testing.T) {
+	items := []Item{{Title: "Visual Studio Code"}}
+	results := Search(items, "code vis")
+	if len(results) != 1 {
+		t.Fatalf("Search returned %d results", len(results))
+	}
+	if want := []int{0, 1, 2, 14, 15, 16, 17}; !reflect.DeepEqual(results[0].Positions, want) {
+		t.Errorf("Positions = %v, want %v", results[0].Positions, want)
+	}
+}
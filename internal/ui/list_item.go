@@ -12,19 +12,26 @@ import (
 
 type AppListItem struct {
 	widget.BaseWidget
-	icon     *widget.Icon
-	label    *widget.Label
-	bg       *canvas.Rectangle
-	selected bool
-	onTapped func()
+	icon           *widget.Icon
+	label          *widget.RichText
+	text           string
+	matches        []int
+	detail         *widget.Label
+	bg             *canvas.Rectangle
+	selected       bool
+	onTapped       func()
+	onDoubleTapped func()
+	onSecondary    func(*fyne.PointEvent)
 }
 
 func NewAppListItem() *AppListItem {
 	item := &AppListItem{
-		icon:  widget.NewIcon(theme.FileApplicationIcon()),
-		label: widget.NewLabel(""),
+		icon:   widget.NewIcon(theme.FileApplicationIcon()),
+		label:  widget.NewRichText(),
+		detail: widget.NewLabel(""),
 	}
-	item.label.Alignment = fyne.TextAlignLeading
+	item.detail.Importance = widget.LowImportance
+	item.detail.Hide()
 	item.label.Truncation = fyne.TextTruncateEllipsis
 	item.ExtendBaseWidget(item)
 	return item
@@ -32,21 +39,44 @@ func NewAppListItem() *AppListItem {
 
 func (i *AppListItem) CreateRenderer() fyne.WidgetRenderer {
 	i.bg = canvas.NewRectangle(color.Transparent)
-	content := container.NewBorder(nil, nil, i.icon, nil, i.label)
+	content := container.NewBorder(nil, nil, i.icon, i.detail, i.label)
 	stack := container.NewMax(i.bg, content)
 	return widget.NewSimpleRenderer(stack)
 }
 
 func (i *AppListItem) Set(icon fyne.Resource, text string) {
+	i.SetWithMatches(icon, text, nil)
+}
+
+// SetWithMatches is like Set but highlights the runes of text at the indexes
+// in matches, such as the characters a search query matched.
+func (i *AppListItem) SetWithMatches(icon fyne.Resource, text string, matches []int) {
 	if icon != nil {
 		i.icon.SetResource(icon)
 	} else {
 		i.icon.SetResource(theme.FileApplicationIcon())
 	}
-	i.label.SetText(text)
+	i.text = text
+	i.matches = matches
 	i.Refresh()
 }
 
+// SetIcon replaces the icon, for example once it has finished loading.
+func (i *AppListItem) SetIcon(icon fyne.Resource) {
+	i.icon.SetResource(icon)
+}
+
+// SetDetail shows text, such as where the application came from, at the
+// trailing edge of the row. An empty string hides it.
+func (i *AppListItem) SetDetail(text string) {
+	i.detail.SetText(text)
+	if text == "" {
+		i.detail.Hide()
+	} else {
+		i.detail.Show()
+	}
+}
+
 func (i *AppListItem) SetSelected(selected bool) {
 	if i.selected == selected {
 		return
@@ -65,21 +95,82 @@ func (i *AppListItem) Tapped(*fyne.PointEvent) {
 	}
 }
 
-func (i *AppListItem) TappedSecondary(*fyne.PointEvent) {}
+func (i *AppListItem) SetOnDoubleTapped(fn func()) {
+	i.onDoubleTapped = fn
+}
+
+func (i *AppListItem) DoubleTapped(*fyne.PointEvent) {
+	if i.onDoubleTapped != nil {
+		i.onDoubleTapped()
+	}
+}
+
+// SetOnTappedSecondary sets the handler for right clicks, such as to show a
+// context menu at the event's position.
+func (i *AppListItem) SetOnTappedSecondary(fn func(*fyne.PointEvent)) {
+	i.onSecondary = fn
+}
+
+func (i *AppListItem) TappedSecondary(ev *fyne.PointEvent) {
+	if i.onSecondary != nil {
+		i.onSecondary(ev)
+	}
+}
 
 func (i *AppListItem) Refresh() {
 	if i.bg != nil {
 		if i.selected {
 			i.bg.FillColor = theme.SelectionColor()
-			i.label.TextStyle = fyne.TextStyle{Bold: true}
 		} else {
 			i.bg.FillColor = color.Transparent
-			i.label.TextStyle = fyne.TextStyle{}
 		}
 		i.bg.Refresh()
 	}
 	if i.icon != nil {
 		i.icon.Refresh()
 	}
+	i.label.Segments = i.segments()
 	i.label.Refresh()
 }
+
+// segments splits the text into runs of matched and unmatched runes. Matched
+// runes are drawn bold in the primary color; the selected row is bold
+// throughout.
+func (i *AppListItem) segments() []widget.RichTextSegment {
+	plain := widget.RichTextStyleInline
+	plain.TextStyle.Bold = i.selected
+	highlight := widget.RichTextStyleInline
+	highlight.TextStyle.Bold = true
+	highlight.ColorName = theme.ColorNamePrimary
+
+	var segments []widget.RichTextSegment
+	var run []rune
+	runMatched, next := false, 0
+	flush := func() {
+		if len(run) == 0 {
+			return
+		}
+		style := plain
+		if runMatched {
+			style = highlight
+		}
+		segments = append(segments, &widget.TextSegment{Text: string(run), Style: style})
+		run = run[:0]
+	}
+	for idx, r := range []rune(i.text) {
+		for next < len(i.matches) && i.matches[next] < idx {
+			next++
+		}
+		matched := next < len(i.matches) && i.matches[next] == idx
+		if matched != runMatched {
+			flush()
+			runMatched = matched
+		}
+		run = append(run, r)
+	}
+	flush()
+	if len(segments) == 0 {
+		segments = append(segments, &widget.TextSegment{Style: plain})
+	}
+	return segments
+}